@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"yabs/common/issues"
+	"gopkg.in/urfave/cli.v2"
+	"gopkg.in/olivere/elastic.v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ISSUE_URL          = `bug_url`
+	ISSUE_FIXED_IN     = `fixed_in`
+	ISSUE_MODULE       = `module`
+	ISSUE_GPU_VENDOR   = `gpu_vendor`
+	ISSUE_GPU_RENDERER = `gpu_renderer`
+	ISSUE_OS_VERSION   = `os_version`
+	ISSUE_MIN_BUILD    = `min_build`
+	ISSUE_MAX_BUILD    = `max_build`
+	ISSUE_DISABLED     = `disabled`
+)
+
+var issueCallbacks = map[string]Callback{
+	"add":    addIssue,
+	"list":   listIssues,
+	"remove": removeIssue,
+}
+
+func IssuesCommand() cli.Command {
+	return cli.Command{
+		Name:      "issues",
+		Usage:     "manage known issue rules: add <id>, list, remove <id>",
+		ArgsUsage: "add|list|remove",
+		Action:    knownIssues,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  URL,
+				Value: "http://127.0.0.1:9200",
+			},
+			cli.StringFlag{
+				Name:  NAME,
+				Usage: "description of the issue",
+			},
+			cli.StringFlag{
+				Name:  ISSUE_URL,
+				Usage: "link to the bug tracker",
+			},
+			cli.StringFlag{
+				Name:  ISSUE_FIXED_IN,
+				Usage: "version with the fix",
+			},
+			cli.StringFlag{
+				Name:  PLATFORM,
+				Usage: "platform of reports",
+			},
+			cli.StringFlag{
+				Name:  SIGNATURE,
+				Usage: "regexp of the signature",
+			},
+			cli.StringFlag{
+				Name:  ISSUE_MODULE,
+				Usage: "regexp of a loaded module",
+			},
+			cli.StringFlag{
+				Name:  ISSUE_GPU_VENDOR,
+				Usage: "regexp of the GPU vendor",
+			},
+			cli.StringFlag{
+				Name:  ISSUE_GPU_RENDERER,
+				Usage: "regexp of the GPU renderer",
+			},
+			cli.StringFlag{
+				Name:  ISSUE_OS_VERSION,
+				Usage: "regexp of the OS version",
+			},
+			cli.StringFlag{
+				Name:  ISSUE_MIN_BUILD,
+				Usage: "first affected build",
+			},
+			cli.StringFlag{
+				Name:  ISSUE_MAX_BUILD,
+				Usage: "last affected build",
+			},
+			cli.BoolFlag{
+				Name:  ISSUE_DISABLED,
+				Usage: "store the rule without matching",
+			},
+		},
+	}
+}
+
+func knownIssues(c *cli.Context) error {
+	initElasticClient(c.String(URL))
+
+	if c.NArg() == 0 {
+		message := `Empty task, available values:
+	add
+	list
+	remove`
+		fmt.Println(message)
+		return fmt.Errorf("Empty task")
+	}
+
+	task := c.Args().Get(0)
+
+	if cb, ok := issueCallbacks[task]; ok {
+		return cb(c, c.Args().Tail())
+	}
+
+	fmt.Printf("Unknown task %s\n", task)
+	return fmt.Errorf("Unknown task %s", task)
+}
+
+// Create or replace the rule
+func addIssue(c *cli.Context, args cli.Args) error {
+	rule := issues.Rule{
+		Id:          args.First(),
+		Title:       c.String(NAME),
+		BugUrl:      c.String(ISSUE_URL),
+		FixedIn:     c.String(ISSUE_FIXED_IN),
+		Platform:    c.String(PLATFORM),
+		Signature:   c.String(SIGNATURE),
+		Module:      c.String(ISSUE_MODULE),
+		GpuVendor:   c.String(ISSUE_GPU_VENDOR),
+		GpuRenderer: c.String(ISSUE_GPU_RENDERER),
+		OsVersion:   c.String(ISSUE_OS_VERSION),
+		MinBuild:    c.String(ISSUE_MIN_BUILD),
+		MaxBuild:    c.String(ISSUE_MAX_BUILD),
+		DateAdded:   time.Now().Format(time.RFC3339),
+		Disabled:    c.Bool(ISSUE_DISABLED),
+	}
+
+	err := rule.Compile()
+	if err != nil {
+		return err
+	}
+
+	_, err = ElasticClient.Index().
+		Index("breakpad").
+		Type("issue").
+		Id(rule.Id).
+		BodyJson(&rule).
+		Refresh("true").
+		Do(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Can't store known issue in Elastic")
+		return err
+	}
+
+	log.WithField("id", rule.Id).Info("Stored known issue")
+	return nil
+}
+
+func listIssues(c *cli.Context, args cli.Args) error {
+	searchResult, err := ElasticClient.Search().
+		Index("breakpad").
+		Type("issue").
+		Query(elastic.NewMatchAllQuery()).
+		Sort("date_added", true).
+		Size(1000).
+		Do(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Can't call to Elastic")
+		return err
+	}
+
+	for _, hit := range searchResult.Hits.Hits {
+		var r issues.Rule
+		err := json.Unmarshal(*hit.Source, &r)
+		if err != nil {
+			log.WithError(err).Warning("Can't deserialize known issue")
+			continue
+		}
+
+		fmt.Printf("%s\t%s\t%s\tfixed_in=%s\tplatform=%s\tsignature=%s\tmodule=%s\tgpu=%s/%s\tos=%s\tbuilds=%s-%s\tdisabled=%t\n",
+			r.Id,
+			r.Title,
+			r.BugUrl,
+			r.FixedIn,
+			r.Platform,
+			r.Signature,
+			r.Module,
+			r.GpuVendor,
+			r.GpuRenderer,
+			r.OsVersion,
+			r.MinBuild,
+			r.MaxBuild,
+			r.Disabled)
+	}
+
+	return nil
+}
+
+func removeIssue(c *cli.Context, args cli.Args) error {
+	id := args.First()
+	if len(id) == 0 {
+		return fmt.Errorf("Known issue id is required")
+	}
+
+	_, err := ElasticClient.Delete().
+		Index("breakpad").
+		Type("issue").
+		Id(id).
+		Refresh("true").
+		Do(context.Background())
+	if elastic.IsNotFound(err) {
+		return fmt.Errorf("Known issue %s isn't found", id)
+	} else if err != nil {
+		log.WithError(err).Error("Can't remove known issue")
+		return err
+	}
+
+	log.WithField("id", id).Info("Removed known issue")
+	return nil
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	"strings"
+	"yabs/common/data/base"
 	"gopkg.in/urfave/cli.v2"
 	"gopkg.in/olivere/elastic.v5"
 	log "github.com/sirupsen/logrus"
@@ -9,9 +11,14 @@ import (
 )
 
 const (
-	URL = `url`
+	URL            = `url`
+	REDIS          = `redis`
+	REDIS_PASSWORD = `redis_password`
+	MEMCACHE       = `memcache`
 )
 var ElasticClient *elastic.Client = nil
+// cache of the collector with versions of symbols
+var SymbolsCache base.Cashe = nil
 
 func init()  {
 	log.SetLevel(log.InfoLevel)
@@ -24,6 +31,11 @@ func main() {
 
 	app.Commands = []cli.Command{
 		RemoveCommand(),
+		TokensCommand(),
+		SymbolsCommand(),
+		UsersCommand(),
+		IssuesCommand(),
+		ModulesCommand(),
 	}
 	app.Run(os.Args)
 }
@@ -38,4 +50,29 @@ func initElasticClient(url string) {
 		}).Fatal("Can't create ElasticSearch client")
 	}
 	ElasticClient = c
-}
\ No newline at end of file
+}
+// Flags of the collector cache, memcache servers are separated by comma
+func cacheFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  REDIS,
+			Value: "localhost:6379",
+			Usage: "redis of the collector",
+		},
+		cli.StringFlag{
+			Name: REDIS_PASSWORD,
+		},
+		cli.StringFlag{
+			Name:  MEMCACHE,
+			Usage: "memcache servers of the collector, used instead of redis",
+		},
+	}
+}
+
+func initSymbolsCache(c *cli.Context) {
+	if len(c.String(MEMCACHE)) != 0 {
+		SymbolsCache, _ = base.NewMemcache(strings.Split(c.String(MEMCACHE), ","))
+	} else if len(c.String(REDIS)) != 0 {
+		SymbolsCache, _ = base.NewRedis(c.String(REDIS), c.String(REDIS_PASSWORD))
+	}
+}
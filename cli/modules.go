@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"yabs/common/format"
+	"gopkg.in/urfave/cli.v2"
+	"gopkg.in/olivere/elastic.v5"
+	log "github.com/sirupsen/logrus"
+)
+
+var moduleCallbacks = map[string]Callback{
+	"top": topModules,
+}
+
+func ModulesCommand() cli.Command {
+	return cli.Command{
+		Name:      "modules",
+		Usage:     "third-party modules in crashes: top",
+		ArgsUsage: "top",
+		Action:    modules,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  URL,
+				Value: "http://127.0.0.1:9200",
+			},
+			cli.IntFlag{
+				Name:  DAYS,
+				Value: 7,
+				Usage: "crashes of the last days",
+			},
+			cli.StringFlag{
+				Name:  PLATFORM,
+				Usage: "platform of crashes",
+			},
+			cli.IntFlag{
+				Name:  SIZE,
+				Value: 20,
+				Usage: "number of modules",
+			},
+		},
+	}
+}
+
+func modules(c *cli.Context) error {
+	initElasticClient(c.String(URL))
+
+	if c.NArg() == 0 {
+		message := `Empty task, available values:
+	top`
+		fmt.Println(message)
+		return fmt.Errorf("Empty task")
+	}
+
+	task := c.Args().Get(0)
+
+	if cb, ok := moduleCallbacks[task]; ok {
+		return cb(c, c.Args().Tail())
+	}
+
+	fmt.Printf("Unknown task %s\n", task)
+	return fmt.Errorf("Unknown task %s", task)
+}
+
+// Third-party modules loaded in the most crashes, hangs aren't counted
+func topModules(c *cli.Context, args cli.Args) error {
+	query := elastic.NewBoolQuery().
+		Must(elastic.NewRangeQuery("date_added").Gte(fmt.Sprintf("now-%dd", c.Int(DAYS)))).
+		MustNot(elastic.NewTermQuery("kind", format.KindHang))
+
+	if platform := c.String(PLATFORM); len(platform) != 0 {
+		query.Must(elastic.NewTermQuery("platform", platform))
+	}
+
+	names := elastic.NewTermsAggregation().
+		Field("third_party_modules.name").
+		Size(c.Int(SIZE)).
+		SubAggregation("vendors", elastic.NewTermsAggregation().Field("third_party_modules.vendor").Size(3)).
+		SubAggregation("versions", elastic.NewTermsAggregation().Field("third_party_modules.version").Size(5))
+
+	searchResult, err := ElasticClient.Search().
+		Index("breakpad").
+		Type("crash").
+		Query(query).
+		Size(0).
+		Aggregation("modules", elastic.NewNestedAggregation().
+			Path("third_party_modules").
+			SubAggregation("names", names)).
+		Do(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Can't call to Elastic")
+		return err
+	}
+
+	nested, ok := searchResult.Aggregations.Nested("modules")
+	if !ok {
+		return nil
+	}
+
+	agg, ok := nested.Aggregations.Terms("names")
+	if !ok {
+		return nil
+	}
+
+	for _, b := range agg.Buckets {
+		fmt.Printf("%s\tcrashes=%d\tvendor=%s\tversions=%s\n",
+			b.Key,
+			b.DocCount,
+			bucketKeys(b.Aggregations, "vendors"),
+			bucketKeys(b.Aggregations, "versions"))
+	}
+
+	return nil
+}
+
+func bucketKeys(aggs elastic.Aggregations, name string) string {
+	agg, ok := aggs.Terms(name)
+	if !ok {
+		return ""
+	}
+
+	var keys []string
+	for _, b := range agg.Buckets {
+		keys = append(keys, fmt.Sprint(b.Key))
+	}
+	return strings.Join(keys, ",")
+}
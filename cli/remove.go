@@ -1,8 +1,11 @@
 package main
 
 import (
-	"os"
 	"fmt"
+	"io"
+	"strconv"
+	"encoding/json"
+	"yabs/common/data/base"
 	"gopkg.in/urfave/cli.v2"
 	"context"
 	"gopkg.in/olivere/elastic.v5"
@@ -15,6 +18,12 @@ const (
 	NAME = `name`
 	SIZE = `count`
 	SHOW = `show_only`
+	SIGNATURE = `signature`
+	USER_ID = `userid`
+	STORAGE = `storage`
+	// filters of crashes, symbols use age and name
+	BUILD = `build`
+	BEFORE = `before`
 )
 
 type Symbol struct {
@@ -25,10 +34,19 @@ type Symbol struct {
 	DataAdded string `json:"date_added"`
 }
 
+type Crash struct {
+	Build     string `json:"build"`
+	Platform  string `json:"platform"`
+	Signature string `json:"signature"`
+	UserId    uint64 `json:"user_id"`
+	DateAdded string `json:"date_added"`
+}
+
 type Callback func(c *cli.Context, args cli.Args) error
 
 var rmCallbacks = map[string]Callback{
 	"symbols": rmSymbols,
+	"crashes": rmCrashes,
 }
 
 func RemoveCommand() cli.Command {
@@ -36,14 +54,16 @@ func RemoveCommand() cli.Command {
 		Name:    "remove",
 		Aliases: []string{"rm"},
 		Action:  remove,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.StringFlag{
 				Name:  AGE,
 				Value: "16d",
+				Usage: "age of symbols",
 			},
 			cli.StringFlag{
 				Name:  NAME,
 				Value: ".*autotests", //Regular expression
+				Usage: "regexp of build of symbols",
 			},
 			cli.StringFlag{
 				Name:URL,
@@ -56,12 +76,37 @@ func RemoveCommand() cli.Command {
 			cli.BoolFlag{
 				Name: SHOW,
 			},
-		},
+			cli.StringFlag{
+				Name:  PLATFORM,
+				Usage: "platform of crashes",
+			},
+			cli.StringFlag{
+				Name:  SIGNATURE,
+				Usage: "signature of crashes",
+			},
+			cli.StringFlag{
+				Name:  USER_ID,
+				Usage: "user id of crashes",
+			},
+			cli.StringFlag{
+				Name:  BUILD,
+				Usage: "regexp of build of crashes",
+			},
+			cli.StringFlag{
+				Name:  BEFORE,
+				Usage: "age of crashes, e.g. 30d",
+			},
+			cli.StringFlag{
+				Name:  STORAGE,
+				Usage: "storage_pathname of the processor with attachments of crashes",
+			},
+		}, cacheFlags()...),
 	}
 }
 
 func remove(c *cli.Context) error {
 	initElasticClient(c.String(URL))
+	initSymbolsCache(c)
 
 	if c.NArg() == 0 {
 		message := `Empty task, available values:
@@ -121,38 +166,159 @@ func rmSymbols(c *cli.Context, args cli.Args) error {
 			continue
 		}
 
-		deleter := elastic.NewDeleteService(ElasticClient).
-			Index("breakpad").
-			Type("symbol").
-			Id(s.DebugId)
-
-		_, err := deleter.Do(context.Background())
+		err := removeSymbol(s)
 		if err != nil {
-			log.WithFields(log.Fields{
-				"error": err,
-				"id": s.DebugId,
-			}).Error("Can't remove document in Elastic")
-
 			return err
 		}
+	}
+
+	return nil
+}
+
+
+func rmCrashes(c *cli.Context, args cli.Args) error {
+	size := c.Int(SIZE)
+	showOnly := c.Bool(SHOW)
+
+	query, err := crashesQuery(c)
+	if err != nil {
+		return err
+	}
+
+	if showOnly {
+		return showCrashes(query, size)
+	}
+
+	if len(c.String(STORAGE)) == 0 {
+		return fmt.Errorf("Storage path is required to remove attachments of crashes")
+	}
+
+	storage, err := base.NewFileStorage(c.String(STORAGE))
+	if err != nil {
+		log.WithError(err).Error("Can't open storage")
+		return err
+	}
+
+	scroll := ElasticClient.Scroll("breakpad").
+		Type("crash").
+		Query(query).
+		Size(size)
+	defer scroll.Clear(context.Background())
+
+	var removed int64
+	for {
+		res, err := scroll.Do(context.Background())
+		if err == io.EOF {
+			break
+		}
 
-		err = os.RemoveAll(s.DirPath)
 		if err != nil {
-			log.WithFields(log.Fields{
-				"error": err,
-				"path": s.DirPath,
-			}).Error("Can't remove directory")
+			log.WithError(err).Error("Can't call to Elastic")
+			return err
+		}
 
+		ids := make([]string, 0, len(res.Hits.Hits))
+		for _, hit := range res.Hits.Hits {
+			ids = append(ids, hit.Id)
+		}
+
+		// files are removed only for crashes which are gone from Elastic
+		deleted, err := base.DeleteCrashes(ElasticClient, ids, "false")
+		if err != nil {
+			log.WithError(err).Error("Can't remove crashes in Elastic")
 			return err
-		} else {
-			log.WithFields(log.Fields{
-				"platform": s.Platform,
-				"version": s.Version,
-				"path": s.DirPath,
-			}).Info("Removed symbols")
 		}
+
+		for _, id := range deleted {
+			err = storage.Remove(base.CrashPrefix(id))
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"id":    id,
+				}).Warning("Can't remove files of crash")
+			}
+		}
+
+		removed += int64(len(deleted))
+		log.WithField("count", removed).Info("Removed crashes")
 	}
 
 	return nil
 }
 
+// Only given filters are applied, at least one is required
+func crashesQuery(c *cli.Context) (elastic.Query, error) {
+	query := elastic.NewBoolQuery()
+	filters := 0
+
+	if before := c.String(BEFORE); len(before) != 0 {
+		query.Must(elastic.NewRangeQuery("date_added").Lte(fmt.Sprintf("now-%s", before)))
+		filters++
+	}
+
+	if build := c.String(BUILD); len(build) != 0 {
+		query.Must(elastic.NewRegexpQuery("build", build))
+		filters++
+	}
+
+	if platform := c.String(PLATFORM); len(platform) != 0 {
+		query.Must(elastic.NewTermQuery("platform", platform))
+		filters++
+	}
+
+	if signature := c.String(SIGNATURE); len(signature) != 0 {
+		query.Must(elastic.NewTermQuery("signature", signature))
+		filters++
+	}
+
+	if userId := c.String(USER_ID); len(userId) != 0 {
+		id, err := strconv.ParseUint(userId, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid user id %s", userId)
+		}
+		query.Must(elastic.NewTermQuery("user_id", id))
+		filters++
+	}
+
+	if filters == 0 {
+		return nil, fmt.Errorf("Filter of crashes is required: %s, %s, %s, %s or %s",
+			BEFORE, BUILD, PLATFORM, SIGNATURE, USER_ID)
+	}
+
+	return query, nil
+}
+
+func showCrashes(query elastic.Query, size int) error {
+	searchResult, err := ElasticClient.Search().
+		Index("breakpad").
+		Type("crash").
+		Query(query).
+		Sort("date_added", true).
+		Size(size).
+		Do(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Can't call to Elastic")
+		return err
+	}
+
+	for _, hit := range searchResult.Hits.Hits {
+		var crash Crash
+		err := json.Unmarshal(*hit.Source, &crash)
+		if err != nil {
+			log.WithError(err).Error("Can't deserialize crash")
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"id":        hit.Id,
+			"platform":  crash.Platform,
+			"version":   crash.Build,
+			"signature": crash.Signature,
+			"user id":   crash.UserId,
+			"date":      crash.DateAdded,
+		}).Info("Crash")
+	}
+
+	log.WithField("total", searchResult.TotalHits()).Info("Crashes")
+	return nil
+}
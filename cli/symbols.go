@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"yabs/common/format"
+	"gopkg.in/urfave/cli.v2"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	COLLECTOR = `collector`
+	TOKEN     = `token`
+	VERSION   = `version`
+	PLATFORM  = `platform`
+	PARALLEL  = `parallel`
+	RETRIES   = `retries`
+	STORE     = `path`
+	REPAIR    = `repair`
+	DAYS      = `days`
+)
+
+const checkBatchSize = 500
+
+type SymbolRef struct {
+	DebugFile string `json:"debug_file"`
+	DebugId   string `json:"debug_id"`
+}
+
+type symbolFile struct {
+	SymbolRef
+	path string
+}
+
+var symbolsCallbacks = map[string]Callback{
+	"upload": uploadSymbols,
+	"fsck":   fsckSymbols,
+	"gc":     gcSymbols,
+}
+
+func SymbolsCommand() cli.Command {
+	return cli.Command{
+		Name:      "symbols",
+		Usage:     "manage symbol files: upload <dir>, fsck, gc",
+		ArgsUsage: "upload|fsck|gc",
+		Action:    symbols,
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name:  COLLECTOR,
+				Value: "http://127.0.0.1:9898",
+			},
+			cli.StringFlag{
+				Name:  TOKEN,
+				Usage: "API token with symbols scope",
+			},
+			cli.StringFlag{
+				Name:  VERSION,
+				Usage: "build version of symbols",
+			},
+			cli.StringFlag{
+				Name:  PLATFORM,
+				Usage: "platform of symbols",
+			},
+			cli.IntFlag{
+				Name:  PARALLEL,
+				Value: 4,
+			},
+			cli.IntFlag{
+				Name:  RETRIES,
+				Value: 3,
+			},
+			cli.StringFlag{
+				Name:  URL,
+				Value: "http://127.0.0.1:9200",
+			},
+			cli.StringFlag{
+				Name:  STORE,
+				Usage: "symbols_pathname of the processor",
+			},
+			cli.BoolFlag{
+				Name:  REPAIR,
+				Usage: "fix found inconsistencies",
+			},
+			cli.IntFlag{
+				Name:  DAYS,
+				Value: 30,
+				Usage: "remove symbols of builds without crashes in the last days",
+			},
+			cli.BoolFlag{
+				Name: SHOW,
+			},
+		}, cacheFlags()...),
+	}
+}
+
+func symbols(c *cli.Context) error {
+	if c.NArg() == 0 {
+		message := `Empty task, available values:
+	upload
+	fsck
+	gc`
+		fmt.Println(message)
+		return fmt.Errorf("Empty task")
+	}
+
+	task := c.Args().Get(0)
+
+	if cb, ok := symbolsCallbacks[task]; ok {
+		return cb(c, c.Args().Tail())
+	}
+
+	fmt.Printf("Unknown task %s\n", task)
+	return fmt.Errorf("Unknown task %s", task)
+}
+
+// Upload symbols of Breakpad symbol directory which are missing on the server
+func uploadSymbols(c *cli.Context, args cli.Args) error {
+	dir := args.First()
+	if len(dir) == 0 {
+		return fmt.Errorf("Symbol directory is required")
+	}
+
+	version := c.String(VERSION)
+	platform := c.String(PLATFORM)
+	if len(version) == 0 || len(platform) == 0 {
+		return fmt.Errorf("Version and platform are required")
+	}
+
+	files, err := findSymbolFiles(dir)
+	if err != nil {
+		log.WithError(err).Error("Can't read symbol directory")
+		return err
+	}
+
+	var missing []symbolFile
+	for start := 0; start < len(files); start += checkBatchSize {
+		end := start + checkBatchSize
+		if end > len(files) {
+			end = len(files)
+		}
+
+		batch, err := checkSymbols(c, files[start:end])
+		if err != nil {
+			return err
+		}
+		missing = append(missing, batch...)
+	}
+
+	log.WithFields(log.Fields{
+		"found":   len(files),
+		"missing": len(missing),
+	}).Info("Checked symbols")
+
+	description, err := json.Marshal(map[string]string{
+		"version":  version,
+		"platform": platform,
+	})
+	if err != nil {
+		return err
+	}
+
+	parallel := c.Int(PARALLEL)
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	queue := make(chan symbolFile)
+	var failed []string
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				err := withRetries(c.Int(RETRIES), func() error {
+					return uploadSymbol(c, f, description)
+				})
+
+				if err != nil {
+					log.WithFields(log.Fields{
+						"error": err,
+						"path":  f.path,
+					}).Error("Can't upload symbol")
+
+					mutex.Lock()
+					failed = append(failed, f.path)
+					mutex.Unlock()
+				} else {
+					log.WithField("path", f.path).Info("Uploaded symbol")
+				}
+			}
+		}()
+	}
+
+	for _, f := range missing {
+		queue <- f
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(failed) != 0 {
+		return fmt.Errorf("Can't upload %d symbol files", len(failed))
+	}
+
+	return nil
+}
+
+func findSymbolFiles(dir string) ([]symbolFile, error) {
+	var files []symbolFile
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".sym") {
+			return nil
+		}
+
+		module, err := format.ModuleFromFile(path)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  path,
+			}).Warning("Skip invalid symbol file")
+			return nil
+		}
+
+		files = append(files, symbolFile{
+			SymbolRef: SymbolRef{
+				DebugFile: module.DebugFile,
+				DebugId:   module.DebugId,
+			},
+			path: path,
+		})
+		return nil
+	})
+
+	return files, err
+}
+
+// Returns files which aren't uploaded yet
+func checkSymbols(c *cli.Context, files []symbolFile) ([]symbolFile, error) {
+	request := struct {
+		Symbols []SymbolRef `json:"symbols"`
+	}{}
+
+	byId := map[string]symbolFile{}
+	for _, f := range files {
+		request.Symbols = append(request.Symbols, f.SymbolRef)
+		byId[f.DebugId] = f
+	}
+
+	body, err := json.Marshal(&request)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply struct {
+		Status  string      `json:"status"`
+		Missing []SymbolRef `json:"missing"`
+	}
+
+	err = withRetries(c.Int(RETRIES), func() error {
+		req, err := http.NewRequest("POST", c.String(COLLECTOR)+"/symbols/check", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		return doRequest(c, req, &reply)
+	})
+
+	if err != nil {
+		log.WithError(err).Error("Can't check symbols")
+		return nil, err
+	}
+
+	var missing []symbolFile
+	for _, s := range reply.Missing {
+		if f, ok := byId[s.DebugId]; ok {
+			missing = append(missing, f)
+		}
+	}
+
+	return missing, nil
+}
+
+func uploadSymbol(c *cli.Context, f symbolFile, description []byte) error {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		writer.CloseWithError(writeSymbolForm(form, f, description))
+	}()
+
+	req, err := http.NewRequest("POST", c.String(COLLECTOR)+"/symbols", reader)
+	if err != nil {
+		reader.Close()
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	err = doRequest(c, req, nil)
+	reader.Close()
+	return err
+}
+
+func writeSymbolForm(form *multipart.Writer, f symbolFile, description []byte) error {
+	part, err := form.CreateFormFile("description", "description.json")
+	if err != nil {
+		return err
+	}
+
+	_, err = part.Write(description)
+	if err != nil {
+		return err
+	}
+
+	part, err = form.CreateFormFile("file", filepath.Base(f.path))
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return err
+	}
+
+	return form.Close()
+}
+
+// Error of the request which mustn't be retried
+type permanentError struct {
+	error
+}
+
+func doRequest(c *cli.Context, req *http.Request, reply interface{}) error {
+	if token := c.String(TOKEN); len(token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("Server error %d: %s", resp.StatusCode, string(data))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &permanentError{fmt.Errorf("Request error %d: %s", resp.StatusCode, string(data))}
+	}
+
+	if reply != nil {
+		return json.Unmarshal(data, reply)
+	}
+
+	return nil
+}
+
+func withRetries(retries int, call func() error) error {
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+
+		err = call()
+		if err == nil {
+			return nil
+		}
+
+		if _, ok := err.(*permanentError); ok {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"error":   err,
+			"attempt": attempt + 1,
+		}).Warning("Request failed")
+	}
+
+	return err
+}
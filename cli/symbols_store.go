@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"gopkg.in/urfave/cli.v2"
+	"gopkg.in/olivere/elastic.v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	webSymbolFile  = "file.symbol"
+	symbolInfoFile = "info.json"
+)
+
+// Find inconsistencies between the symbol store and Elastic:
+// documents without directory, directories without document and incomplete web symbols
+func fsckSymbols(c *cli.Context, args cli.Args) error {
+	initElasticClient(c.String(URL))
+	initSymbolsCache(c)
+
+	if len(c.String(STORE)) == 0 {
+		return fmt.Errorf("Path of the symbol store is required")
+	}
+
+	root, err := storeRoot(c.String(STORE))
+	if err != nil {
+		log.WithError(err).Error("Can't resolve path of the symbol store")
+		return err
+	}
+	repair := c.Bool(REPAIR)
+
+	// documents by their path relative to the store, the processor may mount it elsewhere
+	docs := map[string]Symbol{}
+	err = scrollSymbols(elastic.NewMatchAllQuery(), func(s Symbol) error {
+		docs[storeKey(s.DirPath)] = s
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Can't read symbols from Elastic")
+		return err
+	}
+
+	dirs, names, err := storeDirs(root)
+	if err != nil {
+		log.WithError(err).Error("Can't read symbol store")
+		return err
+	}
+
+	matched := 0
+	for key := range dirs {
+		if _, ok := docs[key]; ok {
+			matched++
+		}
+	}
+
+	// most likely the path or Elastic is wrong, repair would wipe everything
+	if repair && matched == 0 && len(docs)+len(dirs) != 0 {
+		return fmt.Errorf("No directory of %s matches a symbol document, refuse to repair", root)
+	}
+
+	issues := 0
+	for key, s := range docs {
+		dir, ok := dirs[key]
+		if !ok {
+			issues++
+			log.WithFields(log.Fields{
+				"id":   s.DebugId,
+				"path": s.DirPath,
+			}).Warning("Symbol directory doesn't exist")
+
+			if repair {
+				err = deleteSymbolDoc(s.DebugId)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if s.Platform == "web" && !isCompleteWebSymbols(dir) {
+			issues++
+			log.WithFields(log.Fields{
+				"id":   s.DebugId,
+				"path": dir,
+			}).Warning("Web symbol directory is incomplete")
+
+			if repair {
+				s.DirPath = dir
+				err = removeSymbol(s)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for key, dir := range dirs {
+		if _, ok := docs[key]; ok {
+			continue
+		}
+
+		issues++
+		log.WithField("path", dir).Warning("Symbol directory has no document")
+
+		if repair {
+			err = os.RemoveAll(dir)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"path":  dir,
+				}).Error("Can't remove directory")
+				return err
+			}
+		}
+	}
+
+	if repair {
+		for _, name := range names {
+			removeIfEmpty(name)
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"documents":   len(docs),
+		"directories": len(dirs),
+		"matched":     matched,
+		"issues":      issues,
+		"repaired":    repair,
+	}).Info("Checked symbol store")
+
+	if issues != 0 && !repair {
+		return fmt.Errorf("Found %d inconsistencies in the symbol store", issues)
+	}
+
+	return nil
+}
+
+// Absolute path of the store without symlinks
+func storeRoot(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// Path of symbols relative to the store, they are stored as
+// <root>/<name>/<debug id> and <root>/WebSymbols/<id>
+func storeKey(dir string) string {
+	dir = filepath.Clean(dir)
+	return filepath.Join(filepath.Base(filepath.Dir(dir)), filepath.Base(dir))
+}
+
+// Symbol directories of the store by their keys and directories of names
+func storeDirs(root string) (map[string]string, []string, error) {
+	names, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dirs := map[string]string{}
+	var nameDirs []string
+	for _, name := range names {
+		if !name.IsDir() {
+			continue
+		}
+
+		nameDir := filepath.Join(root, name.Name())
+		nameDirs = append(nameDirs, nameDir)
+
+		ids, err := ioutil.ReadDir(nameDir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, id := range ids {
+			dir := filepath.Join(nameDir, id.Name())
+			dirs[storeKey(dir)] = dir
+		}
+	}
+
+	return dirs, nameDirs, nil
+}
+
+// Remove symbols of builds which have no crashes in the last days
+func gcSymbols(c *cli.Context, args cli.Args) error {
+	initElasticClient(c.String(URL))
+	initSymbolsCache(c)
+
+	days := c.Int(DAYS)
+	showOnly := c.Bool(SHOW)
+	since := fmt.Sprintf("now-%dd", days)
+
+	builds, err := crashedBuilds(since)
+	if err != nil {
+		log.WithError(err).Error("Can't get builds of crashes")
+		return err
+	}
+
+	// fresh symbols may belong to a build which isn't released yet
+	query := elastic.NewRangeQuery("date_added").Lte(since)
+
+	removed := 0
+	err = scrollSymbols(query, func(s Symbol) error {
+		if builds[s.Version] {
+			return nil
+		}
+
+		removed++
+		if showOnly {
+			log.WithFields(log.Fields{
+				"platform": s.Platform,
+				"version":  s.Version,
+				"path":     s.DirPath,
+				"date":     s.DataAdded,
+			}).Info("Symbols")
+			return nil
+		}
+
+		return removeSymbol(s)
+	})
+	if err != nil {
+		log.WithError(err).Error("Can't remove unused symbols")
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"active builds": len(builds),
+		"removed":       removed,
+		"show only":     showOnly,
+	}).Info("Collected unused symbols")
+
+	return nil
+}
+
+func crashedBuilds(since string) (map[string]bool, error) {
+	searchResult, err := ElasticClient.Search().
+		Index("breakpad").
+		Type("crash").
+		Query(elastic.NewRangeQuery("date_added").Gte(since)).
+		Size(0).
+		Aggregation("builds", elastic.NewTermsAggregation().Field("build").Size(100000)).
+		Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	builds := map[string]bool{}
+	if agg, ok := searchResult.Aggregations.Terms("builds"); ok {
+		for _, b := range agg.Buckets {
+			builds[fmt.Sprint(b.Key)] = true
+		}
+	}
+
+	return builds, nil
+}
+
+func scrollSymbols(query elastic.Query, walk func(s Symbol) error) error {
+	scroll := ElasticClient.Scroll("breakpad").
+		Type("symbol").
+		Query(query).
+		Size(1000)
+	defer scroll.Clear(context.Background())
+
+	var styp Symbol
+	for {
+		res, err := scroll.Do(context.Background())
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, item := range res.Each(reflect.TypeOf(styp)) {
+			err = walk(item.(Symbol))
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func isCompleteWebSymbols(dir string) bool {
+	for _, name := range []string{webSymbolFile, symbolInfoFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func removeIfEmpty(dir string) {
+	files, err := ioutil.ReadDir(dir)
+	if err == nil && len(files) == 0 {
+		os.Remove(dir)
+	}
+}
+
+// Remove the document and its version in the cache, otherwise the collector
+// reports the symbol as present till the cache expires
+func deleteSymbolDoc(id string) error {
+	_, err := elastic.NewDeleteService(ElasticClient).
+		Index("breakpad").
+		Type("symbol").
+		Id(id).
+		Do(context.Background())
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"id":    id,
+		}).Error("Can't remove document in Elastic")
+		return err
+	}
+
+	if SymbolsCache != nil {
+		err = SymbolsCache.Delete(id)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"id":    id,
+			}).Warning("Can't remove symbol from cache")
+		}
+	}
+
+	return nil
+}
+
+// Remove the document and the directory of symbols
+func removeSymbol(s Symbol) error {
+	err := deleteSymbolDoc(s.DebugId)
+	if err != nil {
+		return err
+	}
+
+	err = os.RemoveAll(s.DirPath)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  s.DirPath,
+		}).Error("Can't remove directory")
+
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"platform": s.Platform,
+		"version":  s.Version,
+		"path":     s.DirPath,
+	}).Info("Removed symbols")
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"yabs/common/data/base"
+	"gopkg.in/urfave/cli.v2"
+	"gopkg.in/olivere/elastic.v5"
+	"github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	TOKEN_NAME     = `name`
+	TOKEN_SCOPE    = `scope`
+	TOKEN_PLATFORM = `platform`
+	TOKEN_BUILD    = `build`
+)
+
+var tokenCallbacks = map[string]Callback{
+	"create": createToken,
+	"list":   listTokens,
+	"revoke": revokeToken,
+}
+
+func TokensCommand() cli.Command {
+	return cli.Command{
+		Name:      "tokens",
+		Usage:     "manage API tokens: create, list, revoke <id>",
+		ArgsUsage: "create|list|revoke",
+		Action:    tokens,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  URL,
+				Value: "http://127.0.0.1:9200",
+			},
+			cli.StringFlag{
+				Name:  TOKEN_NAME,
+				Usage: "name of the new token",
+			},
+			cli.StringSliceFlag{
+				Name:  TOKEN_SCOPE,
+				Usage: "scopes of the new token: symbols, admin",
+			},
+			cli.StringSliceFlag{
+				Name:  TOKEN_PLATFORM,
+				Usage: "platform patterns of the new token, e.g. win",
+			},
+			cli.StringSliceFlag{
+				Name:  TOKEN_BUILD,
+				Usage: "build patterns of the new token, e.g. 5.12.*",
+			},
+		},
+	}
+}
+
+func tokens(c *cli.Context) error {
+	initElasticClient(c.String(URL))
+
+	if c.NArg() == 0 {
+		message := `Empty task, available values:
+	create
+	list
+	revoke`
+		fmt.Println(message)
+		return fmt.Errorf("Empty task")
+	}
+
+	task := c.Args().Get(0)
+
+	if cb, ok := tokenCallbacks[task]; ok {
+		return cb(c, c.Args().Tail())
+	}
+
+	fmt.Printf("Unknown task %s\n", task)
+	return fmt.Errorf("Unknown task %s", task)
+}
+
+func createToken(c *cli.Context, args cli.Args) error {
+	name := c.String(TOKEN_NAME)
+	if len(name) == 0 {
+		return fmt.Errorf("Token name is required")
+	}
+
+	scopes := c.StringSlice(TOKEN_SCOPE)
+	if len(scopes) == 0 {
+		scopes = []string{base.ScopeSymbols}
+	}
+
+	for _, s := range scopes {
+		if s != base.ScopeSymbols && s != base.ScopeAdmin {
+			return fmt.Errorf("Unknown scope %s", s)
+		}
+	}
+
+	secret, err := base.NewTokenSecret()
+	if err != nil {
+		log.WithError(err).Error("Can't generate token")
+		return err
+	}
+
+	t := base.Token{
+		Id:        uuid.NewV4().String(),
+		Name:      name,
+		Hash:      base.HashToken(secret),
+		Scopes:    scopes,
+		Platforms: c.StringSlice(TOKEN_PLATFORM),
+		Builds:    c.StringSlice(TOKEN_BUILD),
+		DateAdded: time.Now().Format(time.RFC3339),
+	}
+
+	_, err = ElasticClient.Index().
+		Index("breakpad").
+		Type("token").
+		Id(t.Hash).
+		BodyJson(&t).
+		Refresh("true").
+		Do(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Can't store token in Elastic")
+		return err
+	}
+
+	fmt.Printf("Token %s (%s) created, keep it secret, it is shown only once:\n%s\n", t.Name, t.Id, secret)
+	return nil
+}
+
+func listTokens(c *cli.Context, args cli.Args) error {
+	searchResult, err := ElasticClient.Search().
+		Index("breakpad").
+		Type("token").
+		Query(elastic.NewMatchAllQuery()).
+		Sort("date_added", true).
+		Size(1000).
+		Do(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Can't call to Elastic")
+		return err
+	}
+
+	var ttyp base.Token
+	for _, item := range searchResult.Each(reflect.TypeOf(ttyp)) {
+		t := item.(base.Token)
+		fmt.Printf("%s\t%s\tscopes=%s\tplatforms=%s\tbuilds=%s\tadded=%s\trevoked=%t\n",
+			t.Id,
+			t.Name,
+			strings.Join(t.Scopes, ","),
+			strings.Join(t.Platforms, ","),
+			strings.Join(t.Builds, ","),
+			t.DateAdded,
+			t.Revoked)
+	}
+
+	return nil
+}
+
+func revokeToken(c *cli.Context, args cli.Args) error {
+	id := args.First()
+	if len(id) == 0 {
+		return fmt.Errorf("Token id is required")
+	}
+
+	searchResult, err := ElasticClient.Search().
+		Index("breakpad").
+		Type("token").
+		Query(elastic.NewTermQuery("id", id)).
+		Do(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Can't call to Elastic")
+		return err
+	}
+
+	if searchResult.Hits == nil || len(searchResult.Hits.Hits) == 0 {
+		return fmt.Errorf("Token %s isn't found", id)
+	}
+
+	for _, hit := range searchResult.Hits.Hits {
+		_, err := ElasticClient.Update().
+			Index("breakpad").
+			Type("token").
+			Id(hit.Id).
+			Doc(map[string]interface{}{"revoked": true}).
+			Refresh("true").
+			Do(context.Background())
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"id":    id,
+			}).Error("Can't revoke token")
+			return err
+		}
+	}
+
+	log.WithField("id", id).Info("Revoked token")
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"yabs/common/data/base"
+	"gopkg.in/urfave/cli.v2"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	OUTPUT = `output`
+)
+
+var usersCallbacks = map[string]Callback{
+	"export": exportUser,
+	"erase":  eraseUser,
+}
+
+func UsersCommand() cli.Command {
+	return cli.Command{
+		Name:      "users",
+		Usage:     "personal data of users: export <id>, erase <id>",
+		ArgsUsage: "export|erase <id>",
+		Action:    users,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  URL,
+				Value: "http://127.0.0.1:9200",
+			},
+			cli.StringFlag{
+				Name:  STORAGE,
+				Usage: "storage_pathname of the processor with attachments of crashes",
+			},
+			cli.StringFlag{
+				Name:  OUTPUT,
+				Usage: "path of the export archive, user-<id>.zip by default",
+			},
+		},
+	}
+}
+
+func users(c *cli.Context) error {
+	if c.NArg() == 0 {
+		message := `Empty task, available values:
+	export
+	erase`
+		fmt.Println(message)
+		return fmt.Errorf("Empty task")
+	}
+
+	task := c.Args().Get(0)
+
+	if cb, ok := usersCallbacks[task]; ok {
+		return cb(c, c.Args().Tail())
+	}
+
+	fmt.Printf("Unknown task %s\n", task)
+	return fmt.Errorf("Unknown task %s", task)
+}
+
+func exportUser(c *cli.Context, args cli.Args) error {
+	userId, rep, storage, err := userTaskArgs(c, args)
+	if err != nil {
+		return err
+	}
+
+	output := c.String(OUTPUT)
+	if len(output) == 0 {
+		output = fmt.Sprintf("user-%d.zip", userId)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		log.WithError(err).Error("Can't create export archive")
+		return err
+	}
+
+	count, err := rep.ExportUser(userId, cliActor(), storage, file)
+	file.Close()
+	if err != nil {
+		os.Remove(output)
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"user id": userId,
+		"reports": count,
+		"path":    output,
+	}).Info("Exported user data")
+
+	return nil
+}
+
+func eraseUser(c *cli.Context, args cli.Args) error {
+	userId, rep, storage, err := userTaskArgs(c, args)
+	if err != nil {
+		return err
+	}
+
+	count, err := rep.EraseUser(userId, cliActor(), storage)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"user id": userId,
+		"reports": count,
+	}).Info("Erased user data")
+
+	return nil
+}
+
+func userTaskArgs(c *cli.Context, args cli.Args) (uint64, *base.Repository, base.Storage, error) {
+	userId, err := strconv.ParseUint(args.First(), 10, 64)
+	if err != nil || userId == 0 {
+		return 0, nil, nil, fmt.Errorf("Invalid user id %q", args.First())
+	}
+
+	if len(c.String(STORAGE)) == 0 {
+		return 0, nil, nil, fmt.Errorf("Storage path is required")
+	}
+
+	storage, err := base.NewFileStorage(c.String(STORAGE))
+	if err != nil {
+		log.WithError(err).Error("Can't open storage")
+		return 0, nil, nil, err
+	}
+
+	// cache is used by symbols only
+	rep, err := base.NewRepository(c.String(URL), nil)
+	if err != nil {
+		log.WithError(err).Error("Can't create ElasticSearch client")
+		return 0, nil, nil, err
+	}
+
+	return userId, rep, storage, nil
+}
+
+// Who is doing the operation, recorded in the audit log
+func cliActor() string {
+	if u, err := user.Current(); err == nil {
+		return "cli:" + u.Username
+	}
+	return "cli"
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"github.com/gin-gonic/gin"
+)
+
+var errTooLarge = errors.New("Request entity too large")
+
+var attachmentNameRx = regexp.MustCompile(`^[\w\-][\w\-.]*$`)
+
+// Form parts of the minidump which aren't attachments
+var minidumpParts = map[string]bool{
+	"upload_file_minidump": true,
+	"info":                 true,
+	"log":                  true,
+}
+
+// Upload all additional files of the minidump form
+func (m *GinCollectorService) uploadAttachments(c *gin.Context) (map[string]string, error) {
+	form := c.Request.MultipartForm
+	if form == nil {
+		return nil, nil
+	}
+
+	var names []string
+	var total int64 = 0
+	for name, headers := range form.File {
+		if minidumpParts[name] || len(headers) == 0 {
+			continue
+		}
+
+		if !attachmentNameRx.MatchString(name) {
+			return nil, fmt.Errorf("Invalid attachment name %.32q", name)
+		}
+
+		if headers[0].Size > m.conf.MaxAttachmentSize() {
+			return nil, errTooLarge
+		}
+
+		total += headers[0].Size
+		names = append(names, name)
+	}
+
+	if len(names) > m.conf.MaxAttachments() {
+		return nil, fmt.Errorf("Too many attachments: %d, max %d", len(names), m.conf.MaxAttachments())
+	}
+
+	if total > m.conf.MaxAttachmentsSize() {
+		return nil, errTooLarge
+	}
+
+	attachments := map[string]string{}
+	for _, name := range names {
+		path, err := m.uploadFile(UploadParams{
+			context: c,
+			param:   name,
+			prefix:  m.prefix("attachment_"),
+			tmpDir:  m.conf.DumpsTmpDir(),
+			maxSize: m.conf.MaxAttachmentSize(),
+		})
+
+		if err == errTooLarge {
+			m.removeAttachments(attachments)
+			return nil, err
+		} else if err != nil {
+			m.removeAttachments(attachments)
+			return nil, fmt.Errorf("Can't upload attachment '%s'", name)
+		}
+		attachments[name] = path
+	}
+
+	return attachments, nil
+}
+
+func (m *GinCollectorService) removeAttachments(attachments map[string]string) {
+	for _, path := range attachments {
+		os.Remove(path)
+	}
+}
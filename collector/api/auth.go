@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"yabs/common/data/base"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const tokenKey = "token"
+
+// Secret from "Authorization: Bearer <token>" or "X-Yabs-Token: <token>"
+func tokenSecret(c *gin.Context) string {
+	auth := c.Request.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+
+	return strings.TrimSpace(c.Request.Header.Get("X-Yabs-Token"))
+}
+
+// Middleware requires an API token with the scope
+func (m *GinCollectorService) authorize(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.conf.AuthEnable() {
+			c.Next()
+			return
+		}
+
+		secret := tokenSecret(c)
+		if len(secret) == 0 {
+			c.JSON(http.StatusUnauthorized, &BaseReply{"error: API token is required"})
+			c.Abort()
+			return
+		}
+
+		token, err := m.service.Token(secret)
+		if err != nil || !token.HasScope(scope) {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  c.Request.URL.Path,
+			}).Debug("Access denied")
+
+			c.JSON(http.StatusForbidden, &BaseReply{"error: Access denied"})
+			c.Abort()
+			return
+		}
+
+		c.Set(tokenKey, token)
+		c.Next()
+	}
+}
+
+// Check platform and build patterns of the request token
+func (m *GinCollectorService) allowed(scope, platform, build string, c *gin.Context) bool {
+	if !m.conf.AuthEnable() {
+		return true
+	}
+
+	token, ok := c.Get(tokenKey)
+	if !ok {
+		return false
+	}
+
+	return token.(*base.Token).Allow(scope, platform, build)
+}
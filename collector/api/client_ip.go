@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"github.com/gin-gonic/gin"
+)
+
+// Address of the client, X-Forwarded-For is used only if the request comes from a trusted proxy.
+// gin's ClientIP trusts the header of any client, so it can't be used for rate limits
+func (m *GinCollectorService) clientIP(c *gin.Context) string {
+	return clientAddress(c.Request, m.proxies)
+}
+
+func clientAddress(r *http.Request, proxies []*net.IPNet) string {
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	// the rightmost address which isn't a trusted proxy is the client,
+	// addresses to the left of it are written by the client itself
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0 && isTrusted(addr, proxies); i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		addr = hop
+	}
+
+	return addr
+}
+
+func isTrusted(addr string, proxies []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, p := range proxies {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"yabs/collector/cfg"
+)
+
+func TestClientAddress(t *testing.T) {
+	proxies, err := cfg.ProxyNetworks([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		client    string
+	}{
+		{"direct", "1.2.3.4:5000", "", "1.2.3.4"},
+		// a client can't choose its address for the limits
+		{"untrusted forwarded", "1.2.3.4:5000", "5.6.7.8", "1.2.3.4"},
+		{"proxy", "10.0.0.1:5000", "5.6.7.8", "5.6.7.8"},
+		{"spoofed behind proxy", "10.0.0.1:5000", "9.9.9.9, 5.6.7.8", "5.6.7.8"},
+		{"chain of proxies", "192.168.1.1:5000", "5.6.7.8, 10.1.1.1", "5.6.7.8"},
+		{"only proxies", "10.0.0.1:5000", "10.0.0.2", "10.0.0.2"},
+		{"invalid hop", "10.0.0.1:5000", "5.6.7.8, garbage", "10.0.0.1"},
+		{"proxy without header", "10.0.0.1:5000", "", "10.0.0.1"},
+	}
+
+	for _, test := range tests {
+		r := &http.Request{RemoteAddr: test.remote, Header: http.Header{}}
+		if len(test.forwarded) != 0 {
+			r.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+
+		if client := clientAddress(r, proxies); client != test.client {
+			t.Errorf("%s: client %q, expected %q", test.name, client, test.client)
+		}
+	}
+}
+
+func TestProxyNetworksInvalid(t *testing.T) {
+	_, err := cfg.ProxyNetworks([]string{"10.0.0.0/8", "proxy.local"})
+	if err == nil {
+		t.Error("expected error of invalid proxy")
+	}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	encodingGzip = "gzip"
+	encodingZstd = "zstd"
+	// max window of zstd frames, 8 MB is the window of zstd levels up to 19 without long mode
+	maxZstdWindow = 8 << 20
+)
+
+// Reader fails when more than limit bytes are read, it guards against zip bombs
+type limitedReader struct {
+	io.ReadCloser
+	left int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.left <= 0 {
+		// data of exactly limit bytes is fine, it's too large only if there is one more byte
+		var probe [1]byte
+		n, err := l.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, errTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+
+	n, err := l.ReadCloser.Read(p)
+	l.left -= int64(n)
+	return n, err
+}
+
+type zstdReadCloser struct {
+	*zstd.Decoder
+	source io.Closer
+}
+
+// Errors of zstd limits are too large data as for limitedReader
+func (z *zstdReadCloser) Read(p []byte) (int, error) {
+	n, err := z.Decoder.Read(p)
+	if err == zstd.ErrDecoderSizeExceeded || err == zstd.ErrWindowSizeExceeded {
+		return n, errTooLarge
+	}
+	return n, err
+}
+
+func (z *zstdReadCloser) Close() error {
+	z.Decoder.Close()
+	return z.source.Close()
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	source io.Closer
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.source.Close()
+}
+
+// Wrap the reader to decompress the data. Limit is a max size of decompressed data
+func decompress(r io.ReadCloser, encoding string, limit int64) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+
+	switch encoding {
+	case encodingGzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		reader = &gzipReadCloser{gz, r}
+	case encodingZstd:
+		// a frame may declare a window up to 3.75 TB, the decoder allocates it before any
+		// data is read, so the window and memory are capped by the limit
+		window := uint64(maxZstdWindow)
+		if uint64(limit) < window {
+			window = uint64(limit)
+		}
+		if window < zstd.MinWindowSize {
+			window = zstd.MinWindowSize
+		}
+
+		// one more byte than the limit, limitedReader tells exact fit from too large data
+		memory := uint64(limit) + 1
+		if memory < window {
+			memory = window
+		}
+
+		zr, err := zstd.NewReader(r,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxWindow(window),
+			zstd.WithDecoderMaxMemory(memory))
+		if err != nil {
+			return nil, err
+		}
+		reader = &zstdReadCloser{zr, r}
+	default:
+		return nil, fmt.Errorf("Unsupported encoding %.32q", encoding)
+	}
+
+	return &limitedReader{reader, limit}, nil
+}
+
+// Encoding of the file by its name: file.sym.gz, file.sym.zst
+func fileEncoding(name string) string {
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, ".gz") {
+		return encodingGzip
+	}
+
+	if strings.HasSuffix(name, ".zst") {
+		return encodingZstd
+	}
+
+	return ""
+}
+
+// Middleware decompresses request bodies with Content-Encoding. It runs after limitBody,
+// so size limits both the compressed and the decompressed body
+func (m *GinCollectorService) decompressBody(size int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" {
+			c.Next()
+			return
+		}
+
+		body, err := decompress(c.Request.Body, encoding, size)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"encoding": encoding,
+			}).Debug("Can't decompress request body")
+
+			c.JSON(http.StatusUnsupportedMediaType, &BaseReply{fmt.Sprintf("error: %s", err.Error())})
+			c.Abort()
+			return
+		}
+
+		limit := &bodyLimit{ReadCloser: body}
+		c.Request.Body = limit
+		c.Request.ContentLength = -1
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Set(decompressedLimitKey, limit)
+		c.Next()
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"github.com/klauspost/compress/zstd"
+)
+
+const testLimit = 16
+
+func TestLimitedReader(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		err  error
+	}{
+		{"empty", 0, nil},
+		{"below limit", testLimit - 1, nil},
+		{"exactly limit", testLimit, nil},
+		{"limit+1", testLimit + 1, errTooLarge},
+		{"far above limit", testLimit * 4, errTooLarge},
+	}
+
+	for _, test := range tests {
+		data := strings.Repeat("a", test.size)
+		reader := &limitedReader{ioutil.NopCloser(strings.NewReader(data)), testLimit}
+
+		read, err := ioutil.ReadAll(reader)
+		if err != test.err {
+			t.Errorf("%s: error %v, expected %v", test.name, err, test.err)
+		}
+
+		if test.err == nil && string(read) != data {
+			t.Errorf("%s: read %d bytes, expected %d", test.name, len(read), test.size)
+		}
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		exceeded bool
+	}{
+		{"exactly limit", testLimit, false},
+		{"limit+1", testLimit + 1, true},
+	}
+
+	for _, test := range tests {
+		body := ioutil.NopCloser(strings.NewReader(strings.Repeat("a", test.size)))
+		limit := &bodyLimit{ReadCloser: &limitedReader{body, testLimit}}
+
+		ioutil.ReadAll(limit)
+		if limit.exceeded != test.exceeded {
+			t.Errorf("%s: exceeded %t, expected %t", test.name, limit.exceeded, test.exceeded)
+		}
+	}
+}
+
+func gzipData(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstdData(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Write([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		size     int
+		err      error
+	}{
+		{"gzip exactly limit", encodingGzip, testLimit, nil},
+		{"gzip limit+1", encodingGzip, testLimit + 1, errTooLarge},
+		// a bomb is small compressed but large decompressed
+		{"gzip bomb", encodingGzip, 1 << 20, errTooLarge},
+		{"zstd exactly limit", encodingZstd, testLimit, nil},
+		{"zstd limit+1", encodingZstd, testLimit + 1, errTooLarge},
+		{"zstd bomb", encodingZstd, 1 << 20, errTooLarge},
+	}
+
+	for _, test := range tests {
+		data := strings.Repeat("a", test.size)
+
+		var compressed []byte
+		if test.encoding == encodingGzip {
+			compressed = gzipData(t, data)
+		} else {
+			compressed = zstdData(t, data)
+		}
+
+		reader, err := decompress(ioutil.NopCloser(bytes.NewReader(compressed)), test.encoding, testLimit)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+
+		read, err := ioutil.ReadAll(reader)
+		if err != test.err {
+			t.Errorf("%s: error %v, expected %v", test.name, err, test.err)
+		}
+
+		if test.err == nil && string(read) != data {
+			t.Errorf("%s: read %d bytes, expected %d", test.name, len(read), test.size)
+		}
+		reader.Close()
+	}
+}
+
+// A frame header may declare a window of hundreds of megabytes which the decoder would
+// allocate by default
+func TestDecompressZstdWindow(t *testing.T) {
+	frame := []byte{
+		0x28, 0xb5, 0x2f, 0xfd, // magic
+		0x00,                   // frame header without content size and single segment
+		0x90,                   // window of 1 << 28 bytes
+		0x01, 0x00, 0x00,       // last raw block of zero size
+	}
+
+	reader, err := decompress(ioutil.NopCloser(bytes.NewReader(frame)), encodingZstd, testLimit)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reader.Close()
+
+	_, err = ioutil.ReadAll(reader)
+	if err != errTooLarge {
+		t.Errorf("error %v, expected %v", err, errTooLarge)
+	}
+}
+
+func TestDecompressInvalid(t *testing.T) {
+	tests := []struct {
+		encoding string
+		data     string
+	}{
+		{"br", "data"},
+		{encodingGzip, "not gzip"},
+	}
+
+	for _, test := range tests {
+		_, err := decompress(ioutil.NopCloser(strings.NewReader(test.data)), test.encoding, testLimit)
+		if err == nil {
+			t.Errorf("%s: expected error", test.encoding)
+		}
+	}
+}
+
+func TestFileEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+	}{
+		{"app.sym", ""},
+		{"app.sym.gz", encodingGzip},
+		{"APP.SYM.GZ", encodingGzip},
+		{"app.sym.zst", encodingZstd},
+		{"app.gz.sym", ""},
+	}
+
+	for _, test := range tests {
+		if actual := fileEncoding(test.name); actual != test.encoding {
+			t.Errorf("fileEncoding(%q) = %q, expected %q", test.name, actual, test.encoding)
+		}
+	}
+}
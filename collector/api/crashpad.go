@@ -0,0 +1,71 @@
+package api
+
+import (
+	"mime/multipart"
+	"strings"
+	"yabs/common/format"
+	"yabs/common/utils"
+)
+
+// Standard fields of Breakpad HTTPUpload and Crashpad handler
+const (
+	crashpadVersion  = "ver"
+	crashpadGuid     = "guid"
+	crashpadPlatform = "plat"
+	crashpadUserId   = "userid"
+	crashpadKind     = "kind"
+)
+
+// Collect plain (not file) fields of the multipart form
+func crashpadFields(form *multipart.Form) map[string]string {
+	fields := map[string]string{}
+	if form == nil {
+		return fields
+	}
+
+	for name, values := range form.Value {
+		if len(values) == 0 || len(strings.TrimSpace(values[0])) == 0 {
+			continue
+		}
+		fields[name] = values[0]
+	}
+
+	return fields
+}
+
+// Map Crashpad fields into the info. Fields which are set by our own
+// info file take precedence, the rest of fields go to annotations
+func applyCrashpadFields(info *format.Info, fields map[string]string) {
+	for name, value := range fields {
+		switch name {
+		case crashpadVersion:
+			if len(info.Version) == 0 {
+				info.Version = utils.Trim(value)
+			}
+		case crashpadGuid:
+			if len(info.InstallId) == 0 {
+				info.InstallId = value
+			}
+		case crashpadPlatform:
+			if len(info.Platform) == 0 {
+				info.Platform = value
+			}
+		case crashpadUserId:
+			if len(info.UserId) == 0 {
+				info.UserId = value
+			}
+		case crashpadKind:
+			if len(info.Kind) == 0 {
+				info.Kind = format.NormalizeKind(value)
+			}
+		default:
+			if info.Annotations == nil {
+				info.Annotations = map[string]string{}
+			}
+
+			if _, ok := info.Annotations[name]; !ok {
+				info.Annotations[name] = value
+			}
+		}
+	}
+}
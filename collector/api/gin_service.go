@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/gin-gonic/gin"
 	"fmt"
+	"net"
 	"net/http"
 	"io/ioutil"
 	"io"
@@ -10,6 +11,9 @@ import (
 	"time"
 	"yabs/collector/cfg"
 	"yabs/collector/service"
+	"yabs/common/format"
+	"yabs/common/task"
+	"yabs/common/data/base"
 	"encoding/json"
 	"bytes"
 	log "github.com/sirupsen/logrus"
@@ -24,18 +28,35 @@ type GinCollectorService struct {
 	engine  *gin.Engine
 	conf    cfg.Config
 	service *service.CollectorService
+	metrics *ginmm.MetricMiddleware
+	proxies []*net.IPNet
 }
 
 type SymbolDescription  struct {
 	Version string `json:"version"`
 	Platform string `json:"platform"`
+	OnDuplicate string `json:"on_duplicate"`
+}
+
+type DiscardReply struct {
+	BaseReply
+	Discarded bool   `json:"discarded"`
+	Reason    string `json:"reason"`
+}
+
+type SymbolUploadReply struct {
+	BaseReply
+	DebugId string `json:"debug_id,omitempty"`
+	Result  string `json:"result"`
 }
 
 type UploadParams struct {
-	context *gin.Context
-	param   string
-	prefix  string
-	tmpDir  string
+	context  *gin.Context
+	param    string
+	prefix   string
+	tmpDir   string
+	maxSize  int64
+	validate headerValidator
 }
 
 func (m *GinCollectorService) Init() error {
@@ -45,14 +66,18 @@ func (m *GinCollectorService) Init() error {
 	var err error = nil
 	m.conf = cfg.GlobalConfig
 	m.engine = gin.Default()
-	mm := ginmm.NewMetricMiddleware(ginmm.MetricParams{
+	m.proxies, err = cfg.ProxyNetworks(m.conf.TrustedProxies())
+	if err != nil {
+		return err
+	}
+	m.metrics = ginmm.NewMetricMiddleware(ginmm.MetricParams{
 		Service:         "crashes",
 		UdpAddres:       m.conf.UdpAddress(),
 		FlushBufferSize: m.conf.FlushBufferSize(),
 		FlushTimeout:    time.Duration(m.conf.FlushTimeout()),
 	})
 
-	m.engine.Use(mm.Middleware())
+	m.engine.Use(m.metrics.Middleware())
 
 	m.service, err = service.NewCollector(m.conf)
 	if err != nil {
@@ -79,6 +104,24 @@ func (m *GinCollectorService) setBadRequest(descr string, c *gin.Context) {
 	c.JSON(http.StatusBadRequest, rMsg)
 }
 
+func (m *GinCollectorService) setTooLarge(descr string, c *gin.Context) {
+	rMsg := &BaseReply{fmt.Sprintf("error: %s", descr)}
+	c.JSON(http.StatusRequestEntityTooLarge, rMsg)
+}
+
+func (m *GinCollectorService) setTooManyRequests(c *gin.Context) {
+	m.countSubmission(SubmitRateLimited, "")
+	rMsg := &BaseReply{"error: Too many requests"}
+	c.JSON(http.StatusTooManyRequests, rMsg)
+}
+
+// Crash is rejected by throttle rules. The reply is 200 as in Socorro,
+// otherwise Crashpad treats the upload as failed and sends the crash again
+func (m *GinCollectorService) setDiscarded(reason string, c *gin.Context) {
+	m.countDiscarded(reason)
+	c.JSON(http.StatusOK, &DiscardReply{BaseReply{SubmitDiscarded}, true, reason})
+}
+
 func (m *GinCollectorService) Start() error {
 	addres := fmt.Sprintf("%s:%d", m.conf.Host(), m.conf.Port())
 	log.WithField("address", addres).Info("Run on")
@@ -86,9 +129,60 @@ func (m *GinCollectorService) Start() error {
 }
 
 func (m *GinCollectorService) applyRoutes() {
-	m.engine.POST("/symbols", m.PostSymbol())
-	m.engine.POST("/submit", m.PostMiniDump())
-	m.engine.POST("/submit/web", m.PostWebDump())
+	symbolSize := 2*m.conf.MaxSymbolSize() + maxInfoSize
+	archiveSize := m.conf.MaxDecompressedSize() + maxInfoSize
+	minidumpSize := m.conf.MaxMinidumpSize() + m.conf.MaxLogSize() + m.conf.MaxAttachmentsSize() + maxInfoSize
+
+	// bodies are decompressed after limitBody, it rejects too large requests by Content-Length
+	m.engine.POST("/symbols",
+		m.authorize(base.ScopeSymbols),
+		m.limitBody(symbolSize),
+		m.decompressBody(symbolSize),
+		m.PostSymbol())
+	m.engine.POST("/symbols/upload",
+		m.authorize(base.ScopeSymbols),
+		m.limitBody(archiveSize),
+		m.decompressBody(archiveSize),
+		m.PostSymbolArchive())
+	m.engine.POST("/symbols/check",
+		m.authorize(base.ScopeSymbols),
+		m.limitBody(maxInfoSize),
+		m.decompressBody(maxInfoSize),
+		m.PostSymbolCheck())
+	m.engine.POST("/submit",
+		m.rateLimit(),
+		m.limitBody(minidumpSize),
+		m.decompressBody(minidumpSize),
+		m.PostMiniDump())
+	m.engine.POST("/submit/web",
+		m.rateLimit(),
+		m.limitBody(m.conf.MaxWebDumpSize()),
+		m.decompressBody(m.conf.MaxWebDumpSize()),
+		m.PostWebDump())
+
+	// without tokens anyone could erase user data, so admin API exists only with auth
+	if !m.conf.AuthEnable() {
+		log.Warning("Auth is disabled, symbol uploads are open to anyone and admin API isn't available")
+		return
+	}
+
+	m.engine.GET("/admin/users/:id/export",
+		m.authorize(base.ScopeAdmin),
+		m.GetUserExport())
+	m.engine.POST("/admin/users/:id/erase",
+		m.authorize(base.ScopeAdmin),
+		m.PostUserErase())
+	m.engine.GET("/admin/issues",
+		m.authorize(base.ScopeAdmin),
+		m.GetIssues())
+	m.engine.PUT("/admin/issues/:id",
+		m.authorize(base.ScopeAdmin),
+		m.limitBody(maxInfoSize),
+		m.decompressBody(maxInfoSize),
+		m.PutIssue())
+	m.engine.DELETE("/admin/issues/:id",
+		m.authorize(base.ScopeAdmin),
+		m.DeleteIssue())
 }
 
 func (m *GinCollectorService) PostSymbol() gin.HandlerFunc {
@@ -96,7 +190,7 @@ func (m *GinCollectorService) PostSymbol() gin.HandlerFunc {
 
 		description, _, err := c.Request.FormFile("description")
 		if err != nil {
-			m.setBadRequest("Missing parameter 'description'", c)
+			m.setUploadError("Missing parameter 'description'", err, c)
 			return
 		}
 		defer description.Close()
@@ -113,8 +207,13 @@ func (m *GinCollectorService) PostSymbol() gin.HandlerFunc {
 		var buf bytes.Buffer
 		w := io.MultiWriter(tpmDescript, &buf)
 
-		_, err = io.Copy(w, description)
-		if err != nil {
+		err = copyValidated(w, description, maxInfoSize, nil)
+		if err == errTooLarge {
+			defer os.Remove(tpmDescript.Name())
+
+			m.setTooLarge("Description is too large", c)
+			return
+		} else if err != nil {
 			defer os.Remove(tpmDescript.Name())
 
 			log.WithError(err).Error("Could not write to temporary description file")
@@ -130,27 +229,88 @@ func (m *GinCollectorService) PostSymbol() gin.HandlerFunc {
 			return
 		}
 
+		if !m.allowed(base.ScopeSymbols, descr.Platform, descr.Version, c) {
+			defer os.Remove(tpmDescript.Name())
+
+			c.JSON(http.StatusForbidden, &BaseReply{"error: Token isn't allowed for the platform or build"})
+			return
+		}
+
+		var validate headerValidator = symbolHeader
+		if descr.Platform == "web" {
+			validate = nil
+		}
+
 		symbolPath, err := m.uploadFile(UploadParams{
-			context: c,
-			param:   "file",
-			prefix:  m.prefix("symbol_"),
-			tmpDir:  m.conf.SymbolsTmpDir(),
+			context:  c,
+			param:    "file",
+			prefix:   m.prefix("symbol_"),
+			tmpDir:   m.conf.SymbolsTmpDir(),
+			maxSize:  m.conf.MaxSymbolSize(),
+			validate: validate,
 		})
 
 		if err != nil {
 			defer os.Remove(tpmDescript.Name())
-			m.setBadRequest("Can't upload 'file'", c)
+			m.setUploadError("Can't upload 'file'", err, c)
 			return
 		}
 
+		result := SymbolAccepted
+		debugId := ""
+		if descr.Platform != "web" {
+			module, err := format.ModuleFromFile(symbolPath)
+			if err != nil {
+				defer os.Remove(symbolPath)
+				defer os.Remove(tpmDescript.Name())
+				m.setBadRequest(err.Error(), c)
+				return
+			}
+			debugId = module.DebugId
+
+			result, err = m.duplicateStatus(debugId, symbolPath, descr.OnDuplicate)
+			if err != nil {
+				defer os.Remove(symbolPath)
+				defer os.Remove(tpmDescript.Name())
+				m.setServerError("Can't check symbol in repository", c)
+				return
+			}
+
+			if result == SymbolConflict && descr.OnDuplicate == base.DuplicateFail {
+				defer os.Remove(symbolPath)
+				defer os.Remove(tpmDescript.Name())
+				c.JSON(http.StatusConflict, &SymbolUploadReply{
+					BaseReply{fmt.Sprintf("error: Symbol %s is already stored with different content", debugId)},
+					debugId,
+					result,
+				})
+				return
+			}
+
+			if result != SymbolAccepted && result != SymbolReplaced {
+				defer os.Remove(symbolPath)
+				defer os.Remove(tpmDescript.Name())
+				c.JSON(http.StatusOK, &SymbolUploadReply{BaseReply{"success"}, debugId, result})
+				return
+			}
+		}
+
 		wasmSymbolPath := ""
 		if descr.Platform == "web" {
-			wasmSymbolPath, _ = m.uploadFile(UploadParams{
+			wasmSymbolPath, err = m.uploadFile(UploadParams{
 				context: c,
 				param:   "file2",
 				prefix:  m.prefix("symbol_"),
 				tmpDir:  m.conf.SymbolsTmpDir(),
+				maxSize: m.conf.MaxSymbolSize(),
 			})
+
+			if err != nil && !isMissingFile(err) {
+				defer os.Remove(symbolPath)
+				defer os.Remove(tpmDescript.Name())
+				m.setUploadError("Can't upload 'file2'", err, c)
+				return
+			}
 		}
 
 		if len(wasmSymbolPath) == 0 {
@@ -179,8 +339,9 @@ func (m *GinCollectorService) PostSymbol() gin.HandlerFunc {
 				"version":     descr.Version,
 				"symbolfile":  symbolPath,
 				"descritpion": tpmDescript.Name(),
+				"result":      result,
 			}).Debug("Send symbol to processor")
-			m.setSuccessStatus(c)
+			c.JSON(http.StatusOK, &SymbolUploadReply{BaseReply{"success"}, debugId, result})
 		}
 	}
 }
@@ -189,27 +350,76 @@ func (m *GinCollectorService) PostMiniDump() gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		dumpPath, err := m.uploadFile(UploadParams{
-			context: c,
-			param:   "upload_file_minidump",
-			prefix:  m.prefix("minidump_"),
-			tmpDir:  m.conf.DumpsTmpDir(),
+			context:  c,
+			param:    "upload_file_minidump",
+			prefix:   m.prefix("minidump_"),
+			tmpDir:   m.conf.DumpsTmpDir(),
+			maxSize:  m.conf.MaxMinidumpSize(),
+			validate: minidumpHeader,
 		})
 
 		if err != nil {
-			m.setBadRequest("Can't upload 'upload_file_minidump'", c)
+			m.setUploadError("Can't upload 'upload_file_minidump'", err, c)
 			return
 		}
 
+		fields := crashpadFields(c.Request.MultipartForm)
+
 		infoPath, err := m.uploadFile(UploadParams{
 			context: c,
 			param:   "info",
 			prefix:  m.prefix("info_"),
 			tmpDir:  m.conf.DumpsTmpDir(),
+			maxSize: maxInfoSize,
 		})
 
+		if err != nil && (len(fields) == 0 || !isMissingFile(err)) {
+			m.setUploadError("Can't upload 'info'", err, c)
+			defer os.Remove(dumpPath)
+			return
+		}
+
+		// the info file of the client isn't replaced by Crashpad fields if it can't be parsed
+		info := &format.Info{}
+		if len(infoPath) != 0 {
+			info, err = format.InfoFromFile(infoPath)
+			if err != nil {
+				defer os.Remove(dumpPath)
+				defer os.Remove(infoPath)
+
+				m.setBadRequest("Can't parse 'info'", c)
+				return
+			}
+		}
+
+		if len(fields) != 0 {
+			applyCrashpadFields(info, fields)
+
+			if len(infoPath) != 0 {
+				os.Remove(infoPath)
+			}
+
+			infoPath, err = m.writeInfo(info)
+			if err != nil {
+				defer os.Remove(dumpPath)
+
+				m.setServerError("Could not create temporary file", c)
+				return
+			}
+		}
+
+		err = info.CheckLimits(m.conf.InfoLimits())
 		if err != nil {
-			m.setBadRequest("Can't upload 'info'", c)
 			defer os.Remove(dumpPath)
+			defer os.Remove(infoPath)
+
+			m.setBadRequest(err.Error(), c)
+			return
+		}
+
+		if !m.admit(info, c) {
+			defer os.Remove(dumpPath)
+			defer os.Remove(infoPath)
 			return
 		}
 
@@ -218,23 +428,64 @@ func (m *GinCollectorService) PostMiniDump() gin.HandlerFunc {
 			param:   "log",
 			prefix:  m.prefix("log_"),
 			tmpDir:  m.conf.DumpsTmpDir(),
+			maxSize: m.conf.MaxLogSize(),
 		})
 
-		err = m.service.AddMinidump(dumpPath, infoPath, logPath)
+		if err != nil && !isMissingFile(err) {
+			defer os.Remove(dumpPath)
+			defer os.Remove(infoPath)
+
+			m.setUploadError("Can't upload 'log'", err, c)
+			return
+		}
+
+		attachments, err := m.uploadAttachments(c)
+		if err != nil {
+			defer os.Remove(dumpPath)
+			defer os.Remove(infoPath)
+			defer os.Remove(logPath)
+
+			if m.isTooLarge(err, c) {
+				m.setTooLarge("Attachments are too large", c)
+			} else {
+				m.setBadRequest(err.Error(), c)
+			}
+			return
+		}
+
+		var result *task.Result
+		if m.service.FastMinidump(fileSize(dumpPath)) {
+			result, err = m.service.ProcessMinidump(dumpPath, infoPath, logPath, attachments)
+		} else {
+			err = m.service.AddMinidump(dumpPath, infoPath, logPath, attachments)
+		}
+
 		if err != nil {
 			defer os.Remove(dumpPath)
 			defer os.Remove(infoPath)
+			defer m.removeAttachments(attachments)
 
 			m.setServerError("Can't add new task to process minidump files", c)
 		} else {
-			m.setSuccessStatus(c)
+			m.countSubmission(SubmitAccepted, "")
+			c.JSON(http.StatusOK, m.submitReply(info, result))
 		}
 	}
 }
 
 func (m *GinCollectorService) PostWebDump() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if c.ContentType() == "application/json" {
+			m.postWebDumpJson(c)
+			return
+		}
+
 		stack := c.Request.FormValue("uploaded")
+		if m.isTooLarge(nil, c) {
+			m.setTooLarge("Web dump is too large", c)
+			return
+		}
+
 		if stack == "" {
 			c.String(http.StatusBadRequest, "Field uploaded can't be empty")
 			return
@@ -258,62 +509,126 @@ func (m *GinCollectorService) PostWebDump() gin.HandlerFunc {
 			return
 		}
 
-		tmpDump, err := ioutil.TempFile(m.conf.DumpsTmpDir(), m.prefix("webdump_"))
+		// fields of other types are skipped by Unmarshal, the rest of info
+		// is still checked, so such fields don't bypass limits
+		var infoLimits format.Info
+		typeErr := json.Unmarshal([]byte(infoData), &infoLimits)
+
+		err = infoLimits.CheckLimits(m.conf.InfoLimits())
 		if err != nil {
-			log.WithError(err).Error("Could not create temporary web-dump file")
-			c.String(http.StatusInternalServerError, "Could not create temporary file")
+			m.setBadRequest(err.Error(), c)
 			return
 		}
 
-		_, err = tmpDump.WriteString(stack)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error": err,
-				"file":  tmpDump.Name(),
-			}).Error("Can't write web-dump to file")
-			m.closeAndRemove(tmpDump)
+		if !m.admit(&infoLimits, c) {
 			return
 		}
 
-		tmpInfo, err := ioutil.TempFile(m.conf.DumpsTmpDir(), m.prefix("webinfo_"))
-		if err != nil {
-			m.closeAndRemove(tmpDump)
-			log.WithError(err).Error("Could not create temporary info file")
-			c.String(http.StatusInternalServerError, "Could not create temporary file")
-			return
+		var parsed *format.Info
+		if typeErr == nil {
+			parsed = &infoLimits
 		}
 
-		newData, err := json.Marshal(&info)
-		if err != nil {
-			m.closeAndRemove(tmpDump)
-			m.closeAndRemove(tmpInfo)
+		m.queueWebDump(c, stack, &info, parsed)
+	}
+}
 
-			log.WithError(err).Error("Could not serialize info")
-			c.String(http.StatusInternalServerError, "Could not serialize info")
-			return
-		}
+func (m *GinCollectorService) postWebDumpJson(c *gin.Context) {
+	var report WebCrashReport
+	err := json.NewDecoder(c.Request.Body).Decode(&report)
+	if m.isTooLarge(err, c) {
+		m.setTooLarge("Web dump is too large", c)
+		return
+	} else if err != nil {
+		log.WithError(err).Debug("Invalid web dump fromat. Need json")
+		m.setBadRequest("Invalid web dump fromat. Need json", c)
+		return
+	}
 
-		_, err = tmpInfo.Write(newData)
-		if err != nil {
-			m.closeAndRemove(tmpDump)
-			m.closeAndRemove(tmpInfo)
+	err = report.Validate()
+	if err != nil {
+		m.setBadRequest(err.Error(), c)
+		return
+	}
 
-			log.WithError(err).Error("Could not create temporary info file")
-			c.String(http.StatusInternalServerError, "Could not create temporary file")
-			return
-		}
+	info := report.Info()
+	err = info.CheckLimits(m.conf.InfoLimits())
+	if err != nil {
+		m.setBadRequest(err.Error(), c)
+		return
+	}
 
-		tmpDump.Close()
-		tmpInfo.Close()
-		err = m.service.AddWebDump(tmpDump.Name(), tmpInfo.Name())
-		if err != nil {
-			m.closeAndRemove(tmpDump)
-			m.closeAndRemove(tmpInfo)
+	if !m.admit(info, c) {
+		return
+	}
 
-			m.setServerError("Can't add new task to process minidump files", c)
-		} else {
-			m.setSuccessStatus(c)
-		}
+	log.WithFields(log.Fields{
+		"stack":   report.Stack,
+		"message": report.Message,
+		"version": report.Version,
+	}).Debug("Catch json web dump")
+
+	m.queueWebDump(c, report.Dump(), info, info)
+}
+
+// Parsed info is used for known issues, it's nil if info isn't in the common format
+func (m *GinCollectorService) queueWebDump(c *gin.Context, stack string, info interface{}, parsed *format.Info) {
+	tmpDump, err := ioutil.TempFile(m.conf.DumpsTmpDir(), m.prefix("webdump_"))
+	if err != nil {
+		log.WithError(err).Error("Could not create temporary web-dump file")
+		c.String(http.StatusInternalServerError, "Could not create temporary file")
+		return
+	}
+
+	_, err = tmpDump.WriteString(stack)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"file":  tmpDump.Name(),
+		}).Error("Can't write web-dump to file")
+		m.closeAndRemove(tmpDump)
+		return
+	}
+
+	tmpInfo, err := ioutil.TempFile(m.conf.DumpsTmpDir(), m.prefix("webinfo_"))
+	if err != nil {
+		m.closeAndRemove(tmpDump)
+		log.WithError(err).Error("Could not create temporary info file")
+		c.String(http.StatusInternalServerError, "Could not create temporary file")
+		return
+	}
+
+	newData, err := json.Marshal(info)
+	if err != nil {
+		m.closeAndRemove(tmpDump)
+		m.closeAndRemove(tmpInfo)
+
+		log.WithError(err).Error("Could not serialize info")
+		c.String(http.StatusInternalServerError, "Could not serialize info")
+		return
+	}
+
+	_, err = tmpInfo.Write(newData)
+	if err != nil {
+		m.closeAndRemove(tmpDump)
+		m.closeAndRemove(tmpInfo)
+
+		log.WithError(err).Error("Could not create temporary info file")
+		c.String(http.StatusInternalServerError, "Could not create temporary file")
+		return
+	}
+
+	tmpDump.Close()
+	tmpInfo.Close()
+	result, err := m.service.ProcessWebDump(tmpDump.Name(), tmpInfo.Name())
+	if err != nil {
+		m.closeAndRemove(tmpDump)
+		m.closeAndRemove(tmpInfo)
+
+		m.setServerError("Can't add new task to process minidump files", c)
+	} else {
+		m.countSubmission(SubmitAccepted, "")
+		c.JSON(http.StatusOK, m.submitReply(parsed, result))
 	}
 }
 
@@ -349,16 +664,62 @@ func (m *GinCollectorService) readDescrition(buff *bytes.Buffer) *SymbolDescript
 		return nil
 	}
 
+	if len(d.OnDuplicate) == 0 {
+		d.OnDuplicate = base.DuplicateSkip
+	}
+
+	if !base.IsDuplicatePolicy(d.OnDuplicate) {
+		log.WithField("on_duplicate", d.OnDuplicate).Error("Unknown duplicate policy")
+		return nil
+	}
+
 	return d
 }
 
+func (m *GinCollectorService) writeInfo(info *format.Info) (string, error) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		log.WithError(err).Error("Could not serialize info")
+		return "", err
+	}
+
+	tmpInfo, err := ioutil.TempFile(m.conf.DumpsTmpDir(), m.prefix("info_"))
+	if err != nil {
+		log.WithError(err).Error("Could not create temporary info file")
+		return "", err
+	}
+
+	_, err = tmpInfo.Write(data)
+	if err != nil {
+		m.closeAndRemove(tmpInfo)
+		log.WithError(err).Error("Could not write temporary info file")
+		return "", err
+	}
+
+	tmpInfo.Close()
+	return tmpInfo.Name(), nil
+}
+
 func (m *GinCollectorService) uploadFile(args UploadParams) (string, error) {
-	file, _, err := args.context.Request.FormFile(args.param)
+	formFile, header, err := args.context.Request.FormFile(args.param)
 	if err != nil {
 		log.WithField("param", args.param).
 			Warning("Upload file: missing parameter")
 		return "", err
 	}
+
+	var file io.ReadCloser = formFile
+	if encoding := fileEncoding(header.Filename); encoding != "" {
+		file, err = decompress(formFile, encoding, m.conf.MaxDecompressedSize())
+		if err != nil {
+			formFile.Close()
+			log.WithFields(log.Fields{
+				"param": args.param,
+				"error": err,
+			}).Warning("Upload file: can't decompress")
+			return "", err
+		}
+	}
 	defer file.Close()
 
 	tmpFile, err := ioutil.TempFile(args.tmpDir,
@@ -371,14 +732,24 @@ func (m *GinCollectorService) uploadFile(args UploadParams) (string, error) {
 
 	defer tmpFile.Close()
 
-	_, err = io.Copy(tmpFile, file)
+	err = copyValidated(tmpFile, file, args.maxSize, args.validate)
 	if err != nil {
 		defer os.Remove(tmpFile.Name())
-		log.WithFields(log.Fields{}).
-			Error("Could not create temporary file")
+		log.WithFields(log.Fields{
+			"param": args.param,
+			"error": err,
+		}).Warning("Could not upload file")
 		return "", err
 	}
 
 	tmpFile.Close()
 	return tmpFile.Name(), nil
-}
\ No newline at end of file
+}
+// Size of the file, 0 if it can't be read
+func fileSize(path string) int64 {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return stat.Size()
+}
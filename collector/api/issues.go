@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"yabs/common/issues"
+	"yabs/common/format"
+	"yabs/common/task"
+	"yabs/common/format/minidump"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Reply of /submit, known issue is set if the crash matches a rule.
+// Crash id and signature are set if the crash is processed by the fast path
+type SubmitReply struct {
+	BaseReply
+	CrashId    string `json:"crash_id,omitempty"`
+	Signature  string `json:"signature,omitempty"`
+	KnownIssue *minidump.KnownIssue `json:"known_issue,omitempty"`
+}
+
+type IssuesReply struct {
+	BaseReply
+	Issues []*issues.Rule `json:"issues"`
+}
+
+func (m *GinCollectorService) GetIssues() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := m.service.Issues()
+		if err != nil {
+			log.WithError(err).Error("Can't get known issues")
+			m.setServerError("Can't get known issues", c)
+			return
+		}
+
+		if rules == nil {
+			rules = []*issues.Rule{}
+		}
+
+		c.JSON(http.StatusOK, &IssuesReply{BaseReply{"success"}, rules})
+	}
+}
+
+// Create or replace the rule with id from the path
+func (m *GinCollectorService) PutIssue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rule issues.Rule
+		err := json.NewDecoder(c.Request.Body).Decode(&rule)
+		if err != nil {
+			m.setBadRequest("Invalid rule format. Need json", c)
+			return
+		}
+
+		rule.Id = c.Param("id")
+		if len(rule.DateAdded) == 0 {
+			rule.DateAdded = time.Now().Format(time.RFC3339)
+		}
+
+		err = rule.Compile()
+		if err != nil {
+			m.setBadRequest(err.Error(), c)
+			return
+		}
+
+		err = m.service.PutIssue(&rule)
+		if err != nil {
+			m.setServerError("Can't store known issue", c)
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"id":    rule.Id,
+			"actor": m.actor(c),
+		}).Info("Put known issue")
+
+		m.setSuccessStatus(c)
+	}
+}
+
+func (m *GinCollectorService) DeleteIssue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		found, err := m.service.RemoveIssue(id)
+		if err != nil {
+			m.setServerError("Can't remove known issue", c)
+			return
+		}
+
+		if !found {
+			c.JSON(http.StatusNotFound, &BaseReply{"error: Known issue isn't found"})
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"id":    id,
+			"actor": m.actor(c),
+		}).Info("Remove known issue")
+
+		m.setSuccessStatus(c)
+	}
+}
+
+// Result of the fast path or the known issue matched by info only
+func (m *GinCollectorService) submitReply(info *format.Info, result *task.Result) *SubmitReply {
+	reply := &SubmitReply{BaseReply: BaseReply{"success"}}
+	if result != nil {
+		reply.CrashId = result.Id
+		reply.Signature = result.Signature
+		reply.KnownIssue = result.KnownIssue
+		return reply
+	}
+
+	if info != nil {
+		if rule := m.service.KnownIssue(info); rule != nil {
+			reply.KnownIssue = rule.Issue()
+		}
+	}
+	return reply
+}
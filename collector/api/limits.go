@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"yabs/common/format"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	bodyLimitKey         = "body_limit"
+	decompressedLimitKey = "decompressed_limit"
+	// info and description files are small json
+	maxInfoSize = 1 << 20
+)
+
+var (
+	minidumpMagic = []byte("MDMP")
+	symbolMagic   = []byte("MODULE ")
+)
+
+// Validates the first bytes of an uploaded file
+type headerValidator func(header []byte) error
+
+func minidumpHeader(header []byte) error {
+	if !bytes.HasPrefix(header, minidumpMagic) {
+		return errors.New("Invalid minidump format")
+	}
+	return nil
+}
+
+func symbolHeader(header []byte) error {
+	if !bytes.HasPrefix(header, symbolMagic) {
+		return errors.New("Invalid symbol file format, need MODULE header")
+	}
+	return nil
+}
+
+// Request body which remembers that the limit was exceeded
+type bodyLimit struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *bodyLimit) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == errTooLarge {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// Middleware rejects requests larger than size bytes
+func (m *GinCollectorService) limitBody(size int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > size {
+			log.WithFields(log.Fields{
+				"path":   c.Request.URL.Path,
+				"length": c.Request.ContentLength,
+				"limit":  size,
+			}).Debug("Reject too large request")
+
+			m.setTooLarge(fmt.Sprintf("Request is larger than %d bytes", size), c)
+			c.Abort()
+			return
+		}
+
+		limit := &bodyLimit{ReadCloser: &limitedReader{c.Request.Body, size}}
+		c.Request.Body = limit
+		c.Set(bodyLimitKey, limit)
+		c.Next()
+	}
+}
+
+// Middleware limits crash submissions per client address
+func (m *GinCollectorService) rateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.service.Allow("ip:" + m.clientIP(c)) {
+			m.setTooManyRequests(c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Check the rate limit of the client installation
+func (m *GinCollectorService) allowClient(info *format.Info) bool {
+	if len(info.InstallId) == 0 {
+		return true
+	}
+	return m.service.Allow("install:" + info.InstallId)
+}
+
+// Check the rate limit and the throttle rules, replies to the client if the crash is rejected
+func (m *GinCollectorService) admit(info *format.Info, c *gin.Context) bool {
+	if !m.allowClient(info) {
+		m.setTooManyRequests(c)
+		return false
+	}
+
+	if accepted, reason := m.service.Accept(info); !accepted {
+		m.setDiscarded(reason, c)
+		return false
+	}
+
+	return true
+}
+
+func (m *GinCollectorService) isTooLarge(err error, c *gin.Context) bool {
+	if err == errTooLarge {
+		return true
+	}
+
+	for _, key := range []string{bodyLimitKey, decompressedLimitKey} {
+		if l, ok := c.Get(key); ok && l.(*bodyLimit).exceeded {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reply 413 if the limit was exceeded otherwise 400
+func (m *GinCollectorService) setUploadError(descr string, err error, c *gin.Context) {
+	if m.isTooLarge(err, c) {
+		m.setTooLarge(descr, c)
+	} else if _, ok := err.(*formatError); ok {
+		m.setBadRequest(err.Error(), c)
+	} else {
+		m.setBadRequest(descr, c)
+	}
+}
+
+type formatError struct {
+	error
+}
+
+// Copy at most size bytes into dst and check the header of data
+func copyValidated(dst io.Writer, src io.Reader, size int64, validate headerValidator) error {
+	reader := bufio.NewReader(src)
+	if validate != nil {
+		header, err := reader.Peek(16)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		err = validate(header)
+		if err != nil {
+			return &formatError{err}
+		}
+	}
+
+	n, err := io.Copy(dst, io.LimitReader(reader, size+1))
+	if err != nil {
+		return err
+	}
+
+	if n > size {
+		return errTooLarge
+	}
+
+	return nil
+}
+
+func isMissingFile(err error) bool {
+	return err == http.ErrMissingFile
+}
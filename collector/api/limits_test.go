@@ -0,0 +1,47 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCopyValidated(t *testing.T) {
+	dump := "MDMP" + strings.Repeat("a", testLimit-4)
+
+	tests := []struct {
+		name     string
+		data     string
+		validate headerValidator
+		err      error
+		format   bool
+	}{
+		{"exactly limit", dump, minidumpHeader, nil, false},
+		{"limit+1", dump + "a", minidumpHeader, errTooLarge, false},
+		{"short", "MDMP", minidumpHeader, nil, false},
+		{"without validation", strings.Repeat("a", testLimit), nil, nil, false},
+		{"invalid header", strings.Repeat("a", testLimit), minidumpHeader, nil, true},
+		{"empty", "", symbolHeader, nil, true},
+		{"symbol", "MODULE Linux x86_64 ID app", symbolHeader, errTooLarge, false},
+	}
+
+	for _, test := range tests {
+		var dst bytes.Buffer
+		err := copyValidated(&dst, strings.NewReader(test.data), testLimit, test.validate)
+
+		if test.format {
+			if _, ok := err.(*formatError); !ok {
+				t.Errorf("%s: error %v, expected format error", test.name, err)
+			}
+			continue
+		}
+
+		if err != test.err {
+			t.Errorf("%s: error %v, expected %v", test.name, err, test.err)
+		}
+
+		if err == nil && dst.String() != test.data {
+			t.Errorf("%s: copied %d bytes, expected %d", test.name, dst.Len(), len(test.data))
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"yabs/collector/service"
+)
+
+// Results of crash submissions
+const (
+	SubmitAccepted    = "accepted"
+	SubmitRateLimited = "rate_limited"
+	SubmitMinVersion  = "min_version"
+	SubmitSampledOut  = "sampled_out"
+	SubmitDiscarded   = "discarded"
+)
+
+// Counts the submission by its result in ginmm metrics next to the request metrics.
+// Status codes can't tell results apart, discarded crashes are replied 200 as accepted ones
+func (m *GinCollectorService) countSubmission(result, rule string) {
+	if m.metrics == nil {
+		return
+	}
+
+	tags := map[string]string{"result": result}
+	if len(rule) != 0 {
+		tags["rule"] = rule
+	}
+	m.metrics.Increment("submissions", tags)
+}
+
+// Reason of the sampler is either the min version or the name of the throttle rule
+func (m *GinCollectorService) countDiscarded(reason string) {
+	if reason == service.ReasonMinVersion {
+		m.countSubmission(SubmitMinVersion, "")
+	} else {
+		m.countSubmission(SubmitSampledOut, reason)
+	}
+}
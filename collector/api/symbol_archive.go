@@ -0,0 +1,324 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"yabs/common/data/base"
+	"yabs/common/format"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Upload status of a symbol file
+const (
+	SymbolAccepted  = "accepted"
+	SymbolDuplicate = "duplicate"
+	SymbolInvalid   = "invalid"
+	SymbolUnchanged = "unchanged"
+	SymbolConflict  = "conflict"
+	SymbolReplaced  = "replaced"
+)
+
+type SymbolFileStatus struct {
+	Path    string `json:"path"`
+	DebugId string `json:"debug_id,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+type SymbolArchiveReply struct {
+	BaseReply
+	Files []SymbolFileStatus `json:"files"`
+}
+
+// Max count of files in a symbol archive
+const maxArchiveEntries = 10000
+
+var errTooManyEntries = fmt.Errorf("Archive has more than %d files", maxArchiveEntries)
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// Called for every regular file of the archive
+type archiveWalker func(name string, r io.Reader) error
+
+func (m *GinCollectorService) PostSymbolArchive() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		descrData, descr, err := m.readDescriptionPart(c)
+		if err != nil {
+			m.setUploadError("Missing or invalid parameter 'description'", err, c)
+			return
+		}
+
+		if descr.Platform == "web" {
+			m.setBadRequest("Web symbols can't be uploaded as archive", c)
+			return
+		}
+
+		if !m.allowed(base.ScopeSymbols, descr.Platform, descr.Version, c) {
+			c.JSON(http.StatusForbidden, &BaseReply{"error: Token isn't allowed for the platform or build"})
+			return
+		}
+
+		archivePath, err := m.uploadFile(UploadParams{
+			context:  c,
+			param:    "archive",
+			prefix:   m.prefix("archive_"),
+			tmpDir:   m.conf.SymbolsTmpDir(),
+			maxSize:  m.conf.MaxDecompressedSize(),
+			validate: archiveHeader,
+		})
+		if err != nil {
+			m.setUploadError("Can't upload 'archive'", err, c)
+			return
+		}
+		defer os.Remove(archivePath)
+
+		// all entries share the budget, so small entries can't fill the temporary dir
+		entries := 0
+		left := m.conf.MaxDecompressedSize()
+
+		files := []SymbolFileStatus{}
+		err = walkArchive(archivePath, func(name string, r io.Reader) error {
+			entries++
+			if entries > maxArchiveEntries {
+				return errTooManyEntries
+			}
+
+			if !strings.HasSuffix(strings.ToLower(name), ".sym") {
+				return nil
+			}
+
+			if left <= 0 {
+				return errTooLarge
+			}
+
+			limited := &limitedReader{ioutil.NopCloser(r), left}
+			files = append(files, m.queueArchiveSymbol(name, limited, descrData, descr.OnDuplicate))
+			left = limited.left
+			return nil
+		})
+
+		// files which were already queued are reported too
+		if err == errTooLarge || err == errTooManyEntries {
+			log.WithError(err).Debug("Symbol archive exceeds limits")
+			c.JSON(http.StatusRequestEntityTooLarge, &SymbolArchiveReply{BaseReply{fmt.Sprintf("error: %s", err.Error())}, files})
+			return
+		} else if err != nil {
+			log.WithError(err).Debug("Can't read symbol archive")
+			c.JSON(http.StatusBadRequest, &SymbolArchiveReply{BaseReply{fmt.Sprintf("error: Can't read archive: %s", err.Error())}, files})
+			return
+		}
+
+		c.JSON(http.StatusOK, &SymbolArchiveReply{BaseReply{"success"}, files})
+	}
+}
+
+// Extract the symbol file <name>/<debugid>/<name>.sym and queue it
+func (m *GinCollectorService) queueArchiveSymbol(name string, r io.Reader, descr []byte, policy string) SymbolFileStatus {
+	status := SymbolFileStatus{Path: name, Status: SymbolInvalid}
+
+	parts := strings.Split(path.Clean(strings.TrimPrefix(name, "/")), "/")
+	if len(parts) < 3 {
+		status.Error = "Path must be <name>/<debugid>/<name>.sym"
+		return status
+	}
+	parts = parts[len(parts)-3:]
+	status.DebugId = parts[1]
+
+	symbol, err := ioutil.TempFile(m.conf.SymbolsTmpDir(), m.prefix("symbol_"))
+	if err != nil {
+		log.WithError(err).Error("Could not create temporary file")
+		status.Error = "Could not create temporary file"
+		return status
+	}
+
+	err = copyValidated(symbol, r, m.conf.MaxSymbolSize(), symbolHeader)
+	symbol.Close()
+	if err != nil {
+		os.Remove(symbol.Name())
+		status.Error = err.Error()
+		return status
+	}
+
+	module, err := format.ModuleFromFile(symbol.Name())
+	if err != nil {
+		os.Remove(symbol.Name())
+		status.Error = err.Error()
+		return status
+	}
+
+	if !strings.EqualFold(module.DebugId, parts[1]) {
+		os.Remove(symbol.Name())
+		status.Error = fmt.Sprintf("Debug id of MODULE %s doesn't match the path", module.DebugId)
+		return status
+	}
+
+	result, err := m.duplicateStatus(module.DebugId, symbol.Name(), policy)
+	if err != nil {
+		os.Remove(symbol.Name())
+		status.Error = "Can't check symbol in repository"
+		return status
+	}
+
+	if result != SymbolAccepted && result != SymbolReplaced {
+		os.Remove(symbol.Name())
+		status.Status = result
+		return status
+	}
+
+	descrPath, err := m.writeDescription(descr)
+	if err != nil {
+		os.Remove(symbol.Name())
+		status.Error = "Could not create temporary file"
+		return status
+	}
+
+	err = m.service.AddSymbol(symbol.Name(), descrPath)
+	if err != nil {
+		os.Remove(symbol.Name())
+		os.Remove(descrPath)
+		status.Error = "Can't add new task to process symbol file"
+		return status
+	}
+
+	status.Status = result
+	return status
+}
+
+// Read the description part of the symbol upload
+func (m *GinCollectorService) readDescriptionPart(c *gin.Context) ([]byte, *SymbolDescription, error) {
+	description, _, err := c.Request.FormFile("description")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer description.Close()
+
+	var buf bytes.Buffer
+	err = copyValidated(&buf, description, maxInfoSize, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	descr := m.readDescrition(&buf)
+	if descr == nil {
+		return nil, nil, &formatError{fmt.Errorf("Description invalid format")}
+	}
+
+	return buf.Bytes(), descr, nil
+}
+
+func (m *GinCollectorService) writeDescription(data []byte) (string, error) {
+	descr, err := ioutil.TempFile(m.conf.SymbolsTmpDir(), m.prefix("description_"))
+	if err != nil {
+		log.WithError(err).Error("Could not create temporary file")
+		return "", err
+	}
+
+	_, err = descr.Write(data)
+	if err != nil {
+		m.closeAndRemove(descr)
+		return "", err
+	}
+
+	descr.Close()
+	return descr.Name(), nil
+}
+
+func archiveHeader(header []byte) error {
+	if bytes.HasPrefix(header, zipMagic) || bytes.HasPrefix(header, gzipMagic) {
+		return nil
+	}
+	return fmt.Errorf("Invalid archive format, need zip or tar.gz")
+}
+
+func walkArchive(archivePath string, walk archiveWalker) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header, err := bufio.NewReader(file).Peek(len(zipMagic))
+	if err != nil {
+		return err
+	}
+
+	if bytes.HasPrefix(header, zipMagic) {
+		return walkZip(archivePath, walk)
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	return walkTarGz(file, walk)
+}
+
+func walkZip(archivePath string, walk archiveWalker) error {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+
+		err = walk(f.Name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func walkTarGz(r io.Reader, walk archiveWalker) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		err = walk(header.Name, archive)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"yabs/collector/service"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const maxCheckSymbols = 10000
+
+type SymbolRef struct {
+	DebugFile string `json:"debug_file"`
+	DebugId   string `json:"debug_id"`
+}
+
+type SymbolCheckRequest struct {
+	Symbols []SymbolRef `json:"symbols"`
+}
+
+type SymbolCheckReply struct {
+	BaseReply
+	Missing []SymbolRef `json:"missing"`
+}
+
+// Returns symbols which aren't uploaded yet
+func (m *GinCollectorService) PostSymbolCheck() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request SymbolCheckRequest
+		err := json.NewDecoder(c.Request.Body).Decode(&request)
+		if m.isTooLarge(err, c) {
+			m.setTooLarge("Request is too large", c)
+			return
+		} else if err != nil {
+			m.setBadRequest("Invalid request format. Need json", c)
+			return
+		}
+
+		if len(request.Symbols) > maxCheckSymbols {
+			m.setBadRequest(fmt.Sprintf("Too many symbols, max %d", maxCheckSymbols), c)
+			return
+		}
+
+		missing := []SymbolRef{}
+		for _, s := range request.Symbols {
+			if len(s.DebugId) == 0 {
+				m.setBadRequest("Field debug_id can't be empty", c)
+				return
+			}
+
+			exist, err := m.service.IsSymbolExist(s.DebugId)
+			if err == service.ErrNoRepository {
+				c.JSON(http.StatusServiceUnavailable, &BaseReply{fmt.Sprintf("error: %s", err.Error())})
+				return
+			} else if err != nil {
+				log.WithError(err).Error("Can't check symbol in repository")
+				m.setServerError("Can't check symbol in repository", c)
+				return
+			}
+
+			if !exist {
+				missing = append(missing, s)
+			}
+		}
+
+		c.JSON(http.StatusOK, &SymbolCheckReply{BaseReply{"success"}, missing})
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"yabs/common/data/base"
+	"yabs/common/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// Decide what happens with the uploaded symbol file if its debug id is already stored.
+// Identical content is a no-op, different content is resolved by the policy
+func (m *GinCollectorService) duplicateStatus(debugId, symbolPath, policy string) (string, error) {
+	stored, err := m.service.StoredSymbol(debugId)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"debug id": debugId,
+			"error":    err,
+		}).Error("Can't check symbol in repository")
+		return "", err
+	}
+
+	if stored == nil {
+		return SymbolAccepted, nil
+	}
+
+	// symbols uploaded before hashes were stored can't be compared
+	if len(stored.Sha1) != 0 {
+		sha1, err := utils.FileSha1Hash(symbolPath)
+		if err != nil {
+			log.WithError(err).Error("Can't calculate sha1 for symbol file")
+			return "", err
+		}
+
+		if sha1 == stored.Sha1 {
+			return SymbolUnchanged, nil
+		}
+	}
+
+	switch policy {
+	case base.DuplicateReplace:
+		return SymbolReplaced, nil
+	case base.DuplicateFail:
+		return SymbolConflict, nil
+	}
+
+	if len(stored.Sha1) == 0 {
+		return SymbolDuplicate, nil
+	}
+	return SymbolConflict, nil
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"yabs/collector/service"
+	"yabs/common/data/base"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+type EraseUserReply struct {
+	BaseReply
+	Reports int `json:"reports"`
+}
+
+// Returns zip archive with reports, logs and attachments of the user
+func (m *GinCollectorService) GetUserExport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil || userId == 0 {
+			m.setBadRequest("Invalid user id", c)
+			return
+		}
+
+		archive, err := ioutil.TempFile(m.conf.DumpsTmpDir(), m.prefix("export_"))
+		if err != nil {
+			log.WithError(err).Error("Could not create temporary file")
+			m.setServerError("Could not create temporary file", c)
+			return
+		}
+		defer os.Remove(archive.Name())
+		defer archive.Close()
+
+		count, err := m.service.ExportUser(userId, m.actor(c), archive)
+		if err != nil {
+			m.setUserDataError("Can't export user data", err, c)
+			return
+		}
+
+		_, err = archive.Seek(0, io.SeekStart)
+		if err != nil {
+			m.setServerError("Can't read exported data", c)
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"user id": userId,
+			"reports": count,
+		}).Info("Export user data")
+
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=user-%d.zip", userId))
+		c.Writer.WriteHeader(http.StatusOK)
+		io.Copy(c.Writer, archive)
+	}
+}
+
+// Removes reports and attachments of the user
+func (m *GinCollectorService) PostUserErase() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil || userId == 0 {
+			m.setBadRequest("Invalid user id", c)
+			return
+		}
+
+		count, err := m.service.EraseUser(userId, m.actor(c))
+		if err != nil {
+			m.setUserDataError("Can't erase user data", err, c)
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"user id": userId,
+			"reports": count,
+		}).Info("Erase user data")
+
+		c.JSON(http.StatusOK, &EraseUserReply{BaseReply{"success"}, count})
+	}
+}
+
+func (m *GinCollectorService) setUserDataError(descr string, err error, c *gin.Context) {
+	if err == service.ErrNoStorage || err == service.ErrNoRepository {
+		c.JSON(http.StatusServiceUnavailable, &BaseReply{fmt.Sprintf("error: %s", err.Error())})
+		return
+	}
+	m.setServerError(descr, c)
+}
+
+// Who is doing the request, recorded in the audit log
+func (m *GinCollectorService) actor(c *gin.Context) string {
+	if token, ok := c.Get(tokenKey); ok {
+		return "token:" + token.(*base.Token).Name
+	}
+	return "ip:" + m.clientIP(c)
+}
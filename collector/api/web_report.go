@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"yabs/common/format"
+	"yabs/common/utils"
+)
+
+// WebCrashReport is the JSON body accepted by POST /submit/web
+type WebCrashReport struct {
+	Stack       string              `json:"stack"`
+	Message     string              `json:"message"`
+	ErrorName   string              `json:"error_name"`
+	Url         string              `json:"url"`
+	UserAgent   string              `json:"user_agent"`
+	Version     string              `json:"build_version"`
+	Platform    string              `json:"platform"`
+	Gpu         format.GPUInfo      `json:"gpu"`
+	UserId      string              `json:"userid"`
+	InstallId   string              `json:"install_id"`
+	Breadcrumbs []format.Breadcrumb `json:"breadcrumbs"`
+	Tags        map[string]string   `json:"tags"`
+	Kind        string              `json:"kind"`
+}
+
+func (w *WebCrashReport) Validate() error {
+	if len(utils.Trim(w.Version)) == 0 {
+		return errors.New("Field build_version can't be empty")
+	}
+
+	if len(strings.TrimSpace(w.Stack)) == 0 && len(strings.TrimSpace(w.Message)) == 0 {
+		return errors.New("Fields stack and message can't be empty both")
+	}
+
+	for i, b := range w.Breadcrumbs {
+		if _, err := time.Parse(time.RFC3339, b.Timestamp); err != nil {
+			return fmt.Errorf("Breadcrumb %d has invalid timestamp, need RFC3339", i)
+		}
+	}
+
+	for k := range w.Tags {
+		if len(strings.TrimSpace(k)) == 0 {
+			return errors.New("Tag name can't be empty")
+		}
+	}
+
+	return nil
+}
+
+// Dump returns the content of the web-dump file for webstackwalker
+func (w *WebCrashReport) Dump() string {
+	if len(strings.TrimSpace(w.Stack)) != 0 {
+		return w.Stack
+	}
+
+	if len(w.ErrorName) != 0 {
+		return fmt.Sprintf("%s: %s", w.ErrorName, w.Message)
+	}
+
+	return w.Message
+}
+
+func (w *WebCrashReport) Info() *format.Info {
+	return &format.Info{
+		Version:      utils.Trim(w.Version),
+		Browser:      w.UserAgent,
+		Gpu:          w.Gpu,
+		Platform:     w.Platform,
+		UserId:       w.UserId,
+		InstallId:    w.InstallId,
+		Url:          w.Url,
+		ErrorName:    w.ErrorName,
+		ErrorMessage: w.Message,
+		Breadcrumbs:  w.Breadcrumbs,
+		Annotations:  w.Tags,
+		Kind:         w.Kind,
+	}
+}
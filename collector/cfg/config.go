@@ -4,13 +4,18 @@ import (
 	"os"
 	"errors"
 	"encoding/json"
+	"fmt"
+	"net"
 	"sync"
+	"yabs/common/format"
 	log "github.com/sirupsen/logrus"
 )
 
 type Config interface {
 	Port() uint
 	Host() string
+	// the client address is taken from X-Forwarded-For only behind these proxies
+	TrustedProxies() []string
 	SymbolsTmpDir() string
 	DumpsTmpDir() string
 	RabbitServer() string
@@ -22,8 +27,60 @@ type Config interface {
 	FlushTimeout() int
 	FlushBufferSize() int
 	UdpAddress() string
+
+	// client annotations and breadcrumbs
+	InfoLimits() format.Limits
+
+	// additional files of minidump
+	MaxAttachments() int
+	MaxAttachmentSize() int64
+	MaxAttachmentsSize() int64
+
+	// max size of decompressed body or file
+	MaxDecompressedSize() int64
+
+	// upload limits per file
+	MaxMinidumpSize() int64
+	MaxLogSize() int64
+	MaxSymbolSize() int64
+	MaxWebDumpSize() int64
+
+	// cache
+	Memcache() []string
+	RedisAddres() string
+	RedisPassword() string
+
+	// rate limit of crash submissions per client
+	RateLimitEnable() bool
+	RateLimitBackend() string
+	RateLimitRate() float64
+	RateLimitBurst() int
+
+	// sampling of crashes by build and platform
+	MinVersion() string
+	ThrottleRules() []ThrottleRule
+
+	// empty if Elastic isn't used, then symbols aren't checked for duplicates and known issues are off
+	ElasticUrl() string
+	// API tokens for symbol uploads and admin endpoints
+	AuthEnable() bool
+	// blob storage of the processor, required by user data endpoints
+	StoragePath() string
+
+	// synchronous processing of web dumps and small minidumps
+	FastPathEnable() bool
+	FastPathQueue() string
+	// milliseconds to wait for the processor
+	FastPathBudget() int
+	FastPathMaxMinidumpSize() int64
 }
 
+// Rate limit backends
+const (
+	RateLimitMemory = "memory"
+	RateLimitCache  = "cache"
+)
+
 var GlobalConfigMutex sync.Mutex
 var GlobalConfig Config
 var GlobalConfigPath string
@@ -52,5 +109,143 @@ func FromJson(pathTo string) (Config, error) {
 		return nil, errors.New("The path to the temporary dump directory is not set")
 	}
 
+	if _, err := ProxyNetworks(jconf.Server.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	if jconf.Annotations == nil {
+		jconf.Annotations = &AnnotationsCfg{}
+	}
+
+	if jconf.Annotations.MaxCount == 0 {
+		jconf.Annotations.MaxCount = 64
+	}
+
+	if jconf.Annotations.MaxSize == 0 {
+		jconf.Annotations.MaxSize = 256
+	}
+
+	if jconf.Annotations.MaxBreadcrumbs == 0 {
+		jconf.Annotations.MaxBreadcrumbs = 100
+	}
+
+	if jconf.Annotations.MaxBreadcrumbSize == 0 {
+		jconf.Annotations.MaxBreadcrumbSize = 1024
+	}
+
+	if jconf.Attachments == nil {
+		jconf.Attachments = &AttachmentsCfg{}
+	}
+
+	if jconf.Attachments.MaxCount == 0 {
+		jconf.Attachments.MaxCount = 8
+	}
+
+	if jconf.Attachments.MaxFileSize == 0 {
+		jconf.Attachments.MaxFileSize = 4 << 20
+	}
+
+	if jconf.Attachments.MaxTotalSize == 0 {
+		jconf.Attachments.MaxTotalSize = 16 << 20
+	}
+
+	if jconf.Decompression == nil || jconf.Decompression.MaxSize == 0 {
+		jconf.Decompression = &DecompressionCfg{
+			MaxSize: 2 << 30,
+		}
+	}
+
+	if jconf.Limits == nil {
+		jconf.Limits = &LimitsCfg{}
+	}
+
+	if jconf.Limits.Minidump == 0 {
+		jconf.Limits.Minidump = 64 << 20
+	}
+
+	if jconf.Limits.Log == 0 {
+		jconf.Limits.Log = 16 << 20
+	}
+
+	if jconf.Limits.Symbols == 0 {
+		jconf.Limits.Symbols = 1 << 30
+	}
+
+	if jconf.Limits.WebDump == 0 {
+		jconf.Limits.WebDump = 1 << 20
+	}
+
+	if jconf.Cache == nil {
+		jconf.Cache = &CacheCfg{}
+	}
+
+	if jconf.Auth == nil {
+		jconf.Auth = &AuthCfg{}
+	}
+
+	if jconf.Auth.Enable && len(jconf.Elastic) == 0 {
+		return nil, errors.New("The elastic url is required by auth")
+	}
+
+	if jconf.Throttle == nil {
+		jconf.Throttle = &ThrottleCfg{}
+	}
+
+	if jconf.RateLimit == nil {
+		jconf.RateLimit = &RateLimitCfg{}
+	}
+
+	if jconf.FastPath == nil {
+		jconf.FastPath = &FastPathCfg{}
+	}
+
+	if jconf.FastPath.Enable {
+		if len(jconf.FastPath.Queue) == 0 {
+			return nil, errors.New("The fast path queue is not set")
+		}
+
+		if jconf.FastPath.BudgetMs <= 0 {
+			jconf.FastPath.BudgetMs = 1500
+		}
+
+		if jconf.FastPath.MaxMinidumpSize <= 0 {
+			jconf.FastPath.MaxMinidumpSize = 1 << 20
+		}
+	}
+
+	if jconf.RateLimit.Enable {
+		if jconf.RateLimit.Rate <= 0 || jconf.RateLimit.Burst <= 0 {
+			return nil, errors.New("Rate and burst of the rate limit must be positive")
+		}
+
+		switch jconf.RateLimit.Backend {
+		case "", RateLimitMemory, RateLimitCache:
+		default:
+			return nil, errors.New("Unknown rate limit backend, need 'memory' or 'cache'")
+		}
+	}
+
 	return &jconf, nil
 }
+
+// Parse trusted proxies, a single address is a network of itself
+func ProxyNetworks(proxies []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, p := range proxies {
+		if ip := net.ParseIP(p); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid trusted proxy %s", p)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -1,8 +1,14 @@
 package cfg
 
+import (
+	"yabs/common/format"
+)
+
 type WebServerCfg struct {
 	Port uint `json:"port"`
 	Host string `json:"host"`
+	// addresses or networks of proxies whose X-Forwarded-For is trusted
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
 type TemproryDirs struct {
@@ -26,12 +32,90 @@ type MonitoringCfg struct {
 	UdpAddress      string `json:"udp_addr"`
 }
 
+type AnnotationsCfg struct {
+	MaxCount          int `json:"max_count"`
+	MaxSize           int `json:"max_size"`
+	MaxBreadcrumbs    int `json:"max_breadcrumbs"`
+	MaxBreadcrumbSize int `json:"max_breadcrumb_size"`
+}
+
+type AttachmentsCfg struct {
+	MaxCount     int   `json:"max_count"`
+	MaxFileSize  int64 `json:"max_file_size"`
+	MaxTotalSize int64 `json:"max_total_size"`
+}
+
+type DecompressionCfg struct {
+	MaxSize int64 `json:"max_size"`
+}
+
+type LimitsCfg struct {
+	Minidump int64 `json:"minidump"`
+	Log      int64 `json:"log"`
+	Symbols  int64 `json:"symbols"`
+	WebDump  int64 `json:"web_dump"`
+}
+
+type RedisCfg struct {
+	Address  string `json:"address"`
+	Password string `json:"password"`
+}
+
+type CacheCfg struct {
+	Memcached []string `json:"memcache"`
+	Redis     RedisCfg `json:"redis"`
+}
+
+type RateLimitCfg struct {
+	Enable  bool    `json:"enable"`
+	Backend string  `json:"backend"`
+	Rate    float64 `json:"rate"`
+	Burst   int     `json:"burst"`
+}
+
+// Crashes matched by all conditions (field name -> regexp) are accepted with probability Accept
+type ThrottleRule struct {
+	Name       string            `json:"name"`
+	Conditions map[string]string `json:"conditions"`
+	Accept     float64           `json:"accept"`
+}
+
+type ThrottleCfg struct {
+	MinVersion string         `json:"min_version"`
+	Rules      []ThrottleRule `json:"rules"`
+}
+
+// Synchronous processing of small crashes, the client gets crash id and known issue
+type FastPathCfg struct {
+	Enable          bool   `json:"enable"`
+	Queue           string `json:"queue"`
+	BudgetMs        int    `json:"budget_ms"`
+	MaxMinidumpSize int64  `json:"max_minidump_size"`
+}
+
+// API tokens stored in Elastic. Auth is disabled if the section is absent,
+// then symbol uploads are open to anyone and the admin API isn't available
+type AuthCfg struct {
+	Enable bool `json:"enable"`
+}
+
 type JsonConfig struct {
 	TemproryDirs *TemproryDirs  `json:"temprory_dirs"`
 	Server       *WebServerCfg  `json:"web_server"`
 	Rabbit       *RabbitCfg     `json:"rabbit_cfg"`
 	Log          *LogCfg        `json:"log"`
 	Monitoring   *MonitoringCfg `json:"monitoring"`
+	Annotations  *AnnotationsCfg `json:"annotations"`
+	Attachments  *AttachmentsCfg `json:"attachments"`
+	Decompression *DecompressionCfg `json:"decompression"`
+	Limits       *LimitsCfg      `json:"limits"`
+	Cache        *CacheCfg       `json:"cache"`
+	RateLimit    *RateLimitCfg   `json:"rate_limit"`
+	Throttle     *ThrottleCfg    `json:"throttle"`
+	Elastic      string          `json:"elastic"`
+	Auth         *AuthCfg        `json:"auth"`
+	Storage      string          `json:"storage_pathname"`
+	FastPath     *FastPathCfg    `json:"fast_path"`
 }
 
 func (cfg *JsonConfig) Port() uint {
@@ -42,6 +126,10 @@ func (cfg *JsonConfig) Host() string {
 	return cfg.Server.Host
 }
 
+func (cfg *JsonConfig) TrustedProxies() []string {
+	return cfg.Server.TrustedProxies
+}
+
 func (cfg *JsonConfig) SymbolsTmpDir() string {
 	return cfg.TemproryDirs.Symbols
 }
@@ -76,4 +164,108 @@ func (cfg *JsonConfig) UdpAddress() string {
 
 func (cfg *JsonConfig) MonitoringEnable() bool {
 	return cfg.Monitoring.Enable
-}
\ No newline at end of file
+}
+
+func (cfg *JsonConfig) InfoLimits() format.Limits {
+	return format.Limits{
+		MaxAnnotations:    cfg.Annotations.MaxCount,
+		MaxAnnotationSize: cfg.Annotations.MaxSize,
+		MaxBreadcrumbs:    cfg.Annotations.MaxBreadcrumbs,
+		MaxBreadcrumbSize: cfg.Annotations.MaxBreadcrumbSize,
+	}
+}
+func (cfg *JsonConfig) MaxAttachments() int {
+	return cfg.Attachments.MaxCount
+}
+
+func (cfg *JsonConfig) MaxAttachmentSize() int64 {
+	return cfg.Attachments.MaxFileSize
+}
+
+func (cfg *JsonConfig) MaxAttachmentsSize() int64 {
+	return cfg.Attachments.MaxTotalSize
+}
+
+func (cfg *JsonConfig) MaxDecompressedSize() int64 {
+	return cfg.Decompression.MaxSize
+}
+
+func (cfg *JsonConfig) MaxMinidumpSize() int64 {
+	return cfg.Limits.Minidump
+}
+
+func (cfg *JsonConfig) MaxLogSize() int64 {
+	return cfg.Limits.Log
+}
+
+func (cfg *JsonConfig) MaxSymbolSize() int64 {
+	return cfg.Limits.Symbols
+}
+
+func (cfg *JsonConfig) MaxWebDumpSize() int64 {
+	return cfg.Limits.WebDump
+}
+
+func (cfg *JsonConfig) Memcache() []string {
+	return cfg.Cache.Memcached
+}
+
+func (cfg *JsonConfig) RedisAddres() string {
+	return cfg.Cache.Redis.Address
+}
+
+func (cfg *JsonConfig) RedisPassword() string {
+	return cfg.Cache.Redis.Password
+}
+
+func (cfg *JsonConfig) RateLimitEnable() bool {
+	return cfg.RateLimit.Enable
+}
+
+func (cfg *JsonConfig) RateLimitBackend() string {
+	return cfg.RateLimit.Backend
+}
+
+func (cfg *JsonConfig) RateLimitRate() float64 {
+	return cfg.RateLimit.Rate
+}
+
+func (cfg *JsonConfig) RateLimitBurst() int {
+	return cfg.RateLimit.Burst
+}
+
+func (cfg *JsonConfig) MinVersion() string {
+	return cfg.Throttle.MinVersion
+}
+
+func (cfg *JsonConfig) ThrottleRules() []ThrottleRule {
+	return cfg.Throttle.Rules
+}
+
+func (cfg *JsonConfig) ElasticUrl() string {
+	return cfg.Elastic
+}
+
+func (cfg *JsonConfig) AuthEnable() bool {
+	return cfg.Auth.Enable
+}
+
+func (cfg *JsonConfig) StoragePath() string {
+	return cfg.Storage
+}
+
+func (cfg *JsonConfig) FastPathEnable() bool {
+	return cfg.FastPath.Enable
+}
+
+func (cfg *JsonConfig) FastPathQueue() string {
+	return cfg.FastPath.Queue
+}
+
+func (cfg *JsonConfig) FastPathBudget() int {
+	return cfg.FastPath.BudgetMs
+}
+
+func (cfg *JsonConfig) FastPathMaxMinidumpSize() int64 {
+	return cfg.FastPath.MaxMinidumpSize
+}
@@ -1,9 +1,14 @@
 package service
 
 import (
+	"io"
+	"time"
+	"yabs/common/issues"
 	"yabs/common/task"
 	"encoding/json"
 	"yabs/collector/cfg"
+	"yabs/common/data/base"
+	"yabs/common/format"
 	"github.com/streadway/amqp"
 	"github.com/go-errors/errors"
 	logger "github.com/sirupsen/logrus"
@@ -16,10 +21,23 @@ type RabbitClient struct {
 }
 
 type CollectorService struct {
-	cfg    cfg.Config
-	rabbit *RabbitClient
+	cfg     cfg.Config
+	rabbit  *RabbitClient
+	cache   base.Cashe
+	limiter Limiter
+	sampler *Sampler
+	repository *base.Repository
+	storage base.Storage
+	issues  *issues.Set
+	fast    *fastPath
 }
 
+var ErrNoStorage = errors.New("Blob storage isn't configured")
+var ErrNoRepository = errors.New("Elastic isn't configured")
+
+// Rules edited through the API are applied after this period
+const knownIssuesTtl = time.Minute
+
 func (s *CollectorService) AddSymbol(symbol string, description string) error {
 	t := task.CreateSymbolTask(symbol, description)
 	msg, err := json.Marshal(t)
@@ -41,8 +59,8 @@ func (s *CollectorService) AddSymbols(symbols []string, description string) erro
 	return s.publish(msg)
 }
 
-func (s *CollectorService) AddMinidump(minidump, info, log string) error {
-	t := task.CreateDumpTask(minidump, info, log)
+func (s *CollectorService) AddMinidump(minidump, info, log string, attachments map[string]string) error {
+	t := task.CreateDumpTask(minidump, info, log, attachments)
 	msg, err := json.Marshal(t)
 	if err != nil {
 		logger.WithError(err).Error("Can't serialize message")
@@ -51,14 +69,149 @@ func (s *CollectorService) AddMinidump(minidump, info, log string) error {
 	return s.publish(msg)
 }
 
-func (s *CollectorService) AddWebDump(webdump string, info string) error {
+// Process the minidump synchronously within the fast path budget.
+// Result is nil if the fast path is disabled, disconnected or the budget is exceeded,
+// the crash is processed asynchronously in this case
+func (s *CollectorService) ProcessMinidump(minidump, info, log string, attachments map[string]string) (*task.Result, error) {
+	t := task.CreateDumpTask(minidump, info, log, attachments)
+	msg, err := json.Marshal(t)
+	if err != nil {
+		logger.WithError(err).Error("Can't serialize message")
+		return nil, err
+	}
+	return s.call(msg)
+}
+
+func (s *CollectorService) ProcessWebDump(webdump string, info string) (*task.Result, error) {
 	t := task.CreateWebDumpTask(webdump, info)
 	msg, err := json.Marshal(t)
 	if err != nil {
 		logger.WithError(err).Error("Can't serialize message")
-		return err
+		return nil, err
 	}
-	return s.publish(msg)
+	return s.call(msg)
+}
+
+// Crashes go to the processing queue if the fast path is off or can't publish them
+func (s *CollectorService) call(msg []byte) (*task.Result, error) {
+	if s.fast == nil {
+		return nil, s.publish(msg)
+	}
+
+	result, err := s.fast.call(msg)
+	if err != nil {
+		logger.WithError(err).Debug("Process crash asynchronously")
+		return nil, s.publish(msg)
+	}
+	return result, nil
+}
+
+// Minidump is small enough for the fast path
+func (s *CollectorService) FastMinidump(size int64) bool {
+	return s.fast != nil && size <= s.cfg.FastPathMaxMinidumpSize()
+}
+
+// Check the rate limit of the client, key is an address or install id
+func (s *CollectorService) Allow(key string) bool {
+	if s.limiter == nil {
+		return true
+	}
+
+	allowed := s.limiter.Allow(key)
+	if !allowed {
+		logger.WithField("key", key).Debug("Rate limit exceeded")
+	}
+	return allowed
+}
+
+// Check the throttle rules, returns false and the reason if the crash is discarded
+func (s *CollectorService) Accept(info *format.Info) (bool, string) {
+	accepted, reason := s.sampler.Accept(info)
+	logger.WithFields(logger.Fields{
+		"accepted": accepted,
+		"rule":     reason,
+		"version":  info.Version,
+		"platform": info.Platform,
+	}).Debug("Throttle crash")
+	return accepted, reason
+}
+
+// Find the API token by its secret
+func (s *CollectorService) Token(secret string) (*base.Token, error) {
+	if s.repository == nil {
+		return nil, ErrNoRepository
+	}
+	return s.repository.GetToken(secret)
+}
+
+func (s *CollectorService) IsSymbolExist(debugId string) (bool, error) {
+	if s.repository == nil {
+		return false, ErrNoRepository
+	}
+	return s.repository.IsExist(&base.Symbol{
+		DebugId: debugId,
+	})
+}
+
+// Stored symbol with its content hash, nil if the debug id is unknown
+func (s *CollectorService) StoredSymbol(debugId string) (*base.Symbol, error) {
+	// without Elastic duplicates aren't detected
+	if s.repository == nil {
+		return nil, nil
+	}
+	return s.repository.GetStoredSymbol(debugId)
+}
+
+// Write all data of the user into zip archive
+func (s *CollectorService) ExportUser(userId uint64, actor string, w io.Writer) (int, error) {
+	if s.repository == nil {
+		return 0, ErrNoRepository
+	}
+	if s.storage == nil {
+		return 0, ErrNoStorage
+	}
+	return s.repository.ExportUser(userId, actor, s.storage, w)
+}
+
+// Remove all data of the user
+func (s *CollectorService) EraseUser(userId uint64, actor string) (int, error) {
+	if s.repository == nil {
+		return 0, ErrNoRepository
+	}
+	if s.storage == nil {
+		return 0, ErrNoStorage
+	}
+	return s.repository.EraseUser(userId, actor, s.storage)
+}
+
+// Known issue of the crash by its info, nil if it isn't known
+func (s *CollectorService) KnownIssue(info *format.Info) *issues.Rule {
+	if s.issues == nil {
+		return nil
+	}
+	return s.issues.MatchInfo(info)
+}
+
+func (s *CollectorService) Issues() ([]*issues.Rule, error) {
+	if s.repository == nil {
+		return nil, ErrNoRepository
+	}
+	return s.repository.GetIssues()
+}
+
+// Create or replace the rule, it must be compiled by the caller
+func (s *CollectorService) PutIssue(rule *issues.Rule) error {
+	if s.repository == nil {
+		return ErrNoRepository
+	}
+	return s.repository.PutIssue(rule)
+}
+
+func (s *CollectorService) RemoveIssue(id string) (bool, error) {
+	if s.repository == nil {
+		return false, ErrNoRepository
+	}
+	return s.repository.RemoveIssue(id)
 }
 
 func (s *CollectorService) publish(msg []byte) error {
@@ -109,5 +262,61 @@ func NewCollector(c cfg.Config) (*CollectorService, error) {
 		return nil, errors.New("Can't connect to rabbit")
 	}
 
-	return &CollectorService{c, client}, nil
+	sampler, err := NewSampler(c.MinVersion(), c.ThrottleRules())
+	if err != nil {
+		logger.WithError(err).Error("Invalid throttle rules")
+		return nil, err
+	}
+
+	s := &CollectorService{cfg: c, rabbit: client, sampler: sampler}
+	s.cache = newCache(c)
+
+	if len(c.ElasticUrl()) != 0 {
+		s.repository, err = base.NewRepository(c.ElasticUrl(), s.cache)
+		if err != nil {
+			logger.WithError(err).Error("Can't create repository")
+			return nil, err
+		}
+
+		s.issues = issues.NewSet(s.repository.GetIssues, knownIssuesTtl)
+	} else {
+		logger.Warning("Elastic isn't configured, symbols aren't checked for duplicates and known issues are off")
+	}
+
+	if c.FastPathEnable() {
+		s.fast, err = newFastPath(c)
+		if err != nil {
+			logger.WithError(err).Error("Can't create fast path")
+			return nil, err
+		}
+	}
+
+	if len(c.StoragePath()) != 0 {
+		s.storage, err = base.NewFileStorage(c.StoragePath())
+		if err != nil {
+			logger.WithError(err).Error("Can't create blob storage")
+			return nil, err
+		}
+	}
+
+	if c.RateLimitEnable() {
+		if c.RateLimitBackend() == cfg.RateLimitCache {
+			s.limiter = NewCacheLimiter(s.cache, c.RateLimitRate(), c.RateLimitBurst())
+		} else {
+			s.limiter = NewMemoryLimiter(c.RateLimitRate(), c.RateLimitBurst())
+		}
+	}
+
+	return s, nil
+}
+
+func newCache(conf cfg.Config) base.Cashe {
+	var cache base.Cashe
+	if len(conf.Memcache()) > 0 {
+		cache, _ = base.NewMemcache(conf.Memcache())
+	} else {
+		cache, _ = base.NewRedis(conf.RedisAddres(),
+			conf.RedisPassword())
+	}
+	return cache
 }
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+	"yabs/collector/cfg"
+	"yabs/common/task"
+	"github.com/satori/go.uuid"
+	"github.com/streadway/amqp"
+	logger "github.com/sirupsen/logrus"
+)
+
+// Delay between attempts to restore the connection of the fast path
+const fastPathRetry = 5 * time.Second
+
+var errFastPathDown = errors.New("Fast path is disconnected")
+
+// Crashes are published into the fast queue with the reply queue of the collector.
+// If the processor doesn't reply within the budget the crash is processed as usual.
+// The fast path has its own connection, it's restored with the reply queue when it's closed
+type fastPath struct {
+	mutex   sync.Mutex
+	server  string
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	queue   string
+	replyTo string
+	budget  time.Duration
+	waiting map[string]chan *task.Result
+}
+
+func newFastPath(c cfg.Config) (*fastPath, error) {
+	f := &fastPath{
+		server:  c.RabbitServer(),
+		queue:   c.FastPathQueue(),
+		budget:  time.Duration(c.FastPathBudget()) * time.Millisecond,
+		waiting: map[string]chan *task.Result{},
+	}
+
+	msgs, closed, err := f.connect()
+	if err != nil {
+		return nil, err
+	}
+	go f.run(msgs, closed)
+
+	return f, nil
+}
+
+// Open the connection, declare the queues and consume replies
+func (f *fastPath) connect() (<-chan amqp.Delivery, chan *amqp.Error, error) {
+	conn, err := amqp.Dial(f.server)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	_, err = ch.QueueDeclare(
+		f.queue,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	// exclusive queue is removed with the connection
+	replies, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	msgs, err := ch.Consume(replies.Name, "", true, true, false, false, nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	// closing of the connection closes the channel too
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	f.mutex.Lock()
+	f.conn = conn
+	f.channel = ch
+	f.replyTo = replies.Name
+	f.mutex.Unlock()
+
+	return msgs, closed, nil
+}
+
+// Receive replies until the channel is closed, then reconnect
+func (f *fastPath) run(msgs <-chan amqp.Delivery, closed chan *amqp.Error) {
+	for {
+		f.receive(msgs)
+
+		reason := <-closed
+		logger.WithField("reason", reason).Error("Fast path is disconnected, crashes are processed asynchronously")
+		f.disconnect()
+
+		for {
+			var err error
+			msgs, closed, err = f.connect()
+			if err == nil {
+				break
+			}
+
+			logger.WithError(err).Warning("Can't restore fast path")
+			time.Sleep(fastPathRetry)
+		}
+		logger.Info("Fast path is restored")
+	}
+}
+
+// Requests waiting for replies of the closed queue get no result and don't wait for the budget
+func (f *fastPath) disconnect() {
+	f.mutex.Lock()
+	if f.conn != nil {
+		f.conn.Close()
+	}
+	f.conn = nil
+	f.channel = nil
+	waiting := f.waiting
+	f.waiting = map[string]chan *task.Result{}
+	f.mutex.Unlock()
+
+	for _, ch := range waiting {
+		ch <- nil
+	}
+}
+
+func (f *fastPath) receive(msgs <-chan amqp.Delivery) {
+	for msg := range msgs {
+		var result task.Result
+		err := json.Unmarshal(msg.Body, &result)
+		if err != nil {
+			logger.WithError(err).Warning("Can't parse task result")
+			continue
+		}
+
+		f.mutex.Lock()
+		ch, ok := f.waiting[msg.CorrelationId]
+		delete(f.waiting, msg.CorrelationId)
+		f.mutex.Unlock()
+
+		// the request could give up already
+		if ok {
+			ch <- &result
+		}
+	}
+}
+
+// Publish the task and wait for its result, nil if the budget is exceeded.
+// errFastPathDown is returned without publishing while the fast path is disconnected
+func (f *fastPath) call(msg []byte) (*task.Result, error) {
+	id := uuid.NewV4().String()
+	ch := make(chan *task.Result, 1)
+
+	f.mutex.Lock()
+	channel, replyTo := f.channel, f.replyTo
+	if channel != nil {
+		f.waiting[id] = ch
+	}
+	f.mutex.Unlock()
+
+	if channel == nil {
+		return nil, errFastPathDown
+	}
+
+	err := channel.Publish("",
+		f.queue,
+		false,
+		false,
+		amqp.Publishing{
+			DeliveryMode:  amqp.Persistent,
+			ContentType:   "text/plain",
+			CorrelationId: id,
+			ReplyTo:       replyTo,
+			Body:          msg,
+		})
+	if err != nil {
+		f.forget(id)
+		return nil, err
+	}
+
+	timer := time.NewTimer(f.budget)
+	defer timer.Stop()
+
+	select {
+	case result := <-ch:
+		return result, nil
+	case <-timer.C:
+		f.forget(id)
+		logger.WithField("id", id).Debug("Fast path budget is exceeded")
+		return nil, nil
+	}
+}
+
+func (f *fastPath) forget(id string) {
+	f.mutex.Lock()
+	delete(f.waiting, id)
+	f.mutex.Unlock()
+}
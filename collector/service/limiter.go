@@ -0,0 +1,142 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+	"yabs/common/data/base"
+	logger "github.com/sirupsen/logrus"
+)
+
+// Token bucket rate limiter
+type Limiter interface {
+	// Take a token from the bucket of the key
+	Allow(key string) bool
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Refill the bucket and take a token
+func (b *bucket) take(now time.Time, rate float64, burst int) bool {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// Buckets are kept in the memory of the collector
+type MemoryLimiter struct {
+	mutex   sync.Mutex
+	rate    float64
+	burst   int
+	buckets map[string]*bucket
+	cleaned time.Time
+	now     func() time.Time
+}
+
+func (l *MemoryLimiter) Allow(key string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+
+	return b.take(now, l.rate, l.burst)
+}
+
+// Forget the full buckets
+func (l *MemoryLimiter) cleanup(now time.Time) {
+	refill := fillTime(l.rate, l.burst)
+	if now.Sub(l.cleaned) < refill {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+	l.cleaned = now
+}
+
+func NewMemoryLimiter(rate float64, burst int) *MemoryLimiter {
+	return &MemoryLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: map[string]*bucket{},
+		cleaned: time.Now(),
+		now:     time.Now,
+	}
+}
+
+// Buckets are shared between collector replicas through the cache.
+// A bucket is approximated by an atomic counter of a fixed window, which is
+// the time to refill the bucket, so burst requests are allowed per window
+type CacheLimiter struct {
+	cache base.Cashe
+	rate  float64
+	burst int
+	now   func() time.Time
+}
+
+func (l *CacheLimiter) Allow(key string) bool {
+	window := windowTime(l.rate, l.burst)
+	key = fmt.Sprintf("ratelimit:%s:%d", key, l.now().UnixNano()/int64(window))
+
+	count, err := l.cache.Incr(key, window)
+	if err != nil {
+		// clients aren't rejected while the cache is down
+		logger.WithFields(logger.Fields{
+			"error": err,
+			"key":   key,
+		}).Warning("Can't count request for rate limit")
+		return true
+	}
+
+	// a window longer than the bucket allows the requests of its rate
+	limit := math.Max(float64(l.burst), l.rate*window.Seconds())
+	return float64(count) <= limit
+}
+
+func NewCacheLimiter(cache base.Cashe, rate float64, burst int) *CacheLimiter {
+	return &CacheLimiter{
+		cache: cache,
+		rate:  rate,
+		burst: burst,
+		now:   time.Now,
+	}
+}
+
+// Window of the cache counter, at least a second
+func windowTime(rate float64, burst int) time.Duration {
+	if rate <= 0 {
+		return time.Hour
+	}
+	return time.Duration(math.Max(float64(burst)/rate, 1) * float64(time.Second))
+}
+
+// Time to refill the empty bucket
+func fillTime(rate float64, burst int) time.Duration {
+	if rate <= 0 {
+		return time.Hour
+	}
+	return time.Duration(float64(burst)/rate*float64(time.Second)) + time.Second
+}
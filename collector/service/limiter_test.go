@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Counters of the cache without expiration, enough for fixed clocks of the tests
+type memoryCache struct {
+	mutex    sync.Mutex
+	counters map[string]int64
+	down     bool
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{counters: map[string]int64{}}
+}
+
+func (c *memoryCache) Get(key string) (string, error)                   { return "", errors.New("miss") }
+func (c *memoryCache) Set(key, value string) error                      { return nil }
+func (c *memoryCache) SetTtl(key, value string, ttl time.Duration) error { return nil }
+func (c *memoryCache) Delete(key string) error                          { return nil }
+
+func (c *memoryCache) Incr(key string, ttl time.Duration) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.down {
+		return 0, errors.New("cache is down")
+	}
+	c.counters[key]++
+	return c.counters[key], nil
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func allowed(l Limiter, key string, n int) int {
+	count := 0
+	for i := 0; i < n; i++ {
+		if l.Allow(key) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	c := &clock{now: time.Unix(1500000000, 0)}
+	l := NewMemoryLimiter(0.5, 3)
+	l.now = c.Now
+
+	// the full bucket gives burst requests
+	if n := allowed(l, "a", 5); n != 3 {
+		t.Errorf("burst: allowed %d, expected 3", n)
+	}
+
+	// buckets are separate per key
+	if n := allowed(l, "b", 1); n != 1 {
+		t.Errorf("other key: allowed %d, expected 1", n)
+	}
+
+	// a token is refilled in 2 seconds
+	c.Add(time.Second)
+	if n := allowed(l, "a", 1); n != 0 {
+		t.Errorf("half token: allowed %d, expected 0", n)
+	}
+
+	c.Add(time.Second)
+	if n := allowed(l, "a", 2); n != 1 {
+		t.Errorf("one token: allowed %d, expected 1", n)
+	}
+
+	// the bucket isn't filled above burst
+	c.Add(time.Hour)
+	if n := allowed(l, "a", 5); n != 3 {
+		t.Errorf("refilled: allowed %d, expected 3", n)
+	}
+}
+
+func TestCacheLimiter(t *testing.T) {
+	c := &clock{now: time.Unix(1500000000, 0)}
+	cache := newMemoryCache()
+	l := NewCacheLimiter(cache, 0.5, 3)
+	l.now = c.Now
+
+	// the window is 6 seconds to refill the bucket, burst requests are allowed per window
+	if n := allowed(l, "a", 5); n != 3 {
+		t.Errorf("burst: allowed %d, expected 3", n)
+	}
+
+	if n := allowed(l, "b", 1); n != 1 {
+		t.Errorf("other key: allowed %d, expected 1", n)
+	}
+
+	c.Add(6 * time.Second)
+	if n := allowed(l, "a", 5); n != 3 {
+		t.Errorf("next window: allowed %d, expected 3", n)
+	}
+
+	// requests aren't rejected while the cache is down
+	cache.down = true
+	if n := allowed(l, "a", 5); n != 5 {
+		t.Errorf("cache down: allowed %d, expected 5", n)
+	}
+}
+
+func TestCacheLimiterConcurrent(t *testing.T) {
+	c := &clock{now: time.Unix(1500000000, 0)}
+	l := NewCacheLimiter(newMemoryCache(), 1, 10)
+	l.now = c.Now
+
+	// replicas share the counter, so no more than burst requests pass together
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	count := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.Allow("a") {
+				mutex.Lock()
+				count++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count != 10 {
+		t.Errorf("allowed %d, expected 10", count)
+	}
+}
+
+func TestWindowTime(t *testing.T) {
+	tests := []struct {
+		rate   float64
+		burst  int
+		window time.Duration
+		limit  int
+	}{
+		{0.5, 3, 6 * time.Second, 3},
+		// a window is at least a second, it allows the requests of the rate
+		{10, 5, time.Second, 10},
+	}
+
+	for _, test := range tests {
+		if w := windowTime(test.rate, test.burst); w != test.window {
+			t.Errorf("rate %v burst %d: window %v, expected %v", test.rate, test.burst, w, test.window)
+		}
+
+		c := &clock{now: time.Unix(1500000000, 0)}
+		l := NewCacheLimiter(newMemoryCache(), test.rate, test.burst)
+		l.now = c.Now
+		if n := allowed(l, "a", 20); n != test.limit {
+			t.Errorf("rate %v burst %d: allowed %d, expected %d", test.rate, test.burst, n, test.limit)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"yabs/collector/cfg"
+	"yabs/common/format"
+	"yabs/common/utils"
+)
+
+// Reason of discarded crashes of versions below the min version
+const ReasonMinVersion = "min_version"
+
+type sampleRule struct {
+	name       string
+	conditions map[string]*regexp.Regexp
+	accept     float64
+}
+
+func (r *sampleRule) match(info *format.Info) bool {
+	for field, rx := range r.conditions {
+		if !rx.MatchString(info.Field(field)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Sampler decides which crashes to accept by throttle rules.
+// The first matched rule wins, crashes without matched rule are accepted
+type Sampler struct {
+	minVersion string
+	rules      []sampleRule
+}
+
+// Returns false and the reason if the crash must be discarded
+func (s *Sampler) Accept(info *format.Info) (bool, string) {
+	if len(s.minVersion) != 0 && utils.CompareVersions(info.Version, s.minVersion) < 0 {
+		return false, ReasonMinVersion
+	}
+
+	for _, r := range s.rules {
+		if r.match(info) {
+			if rand.Float64() < r.accept {
+				return true, r.name
+			}
+			return false, r.name
+		}
+	}
+
+	return true, ""
+}
+
+func NewSampler(minVersion string, rules []cfg.ThrottleRule) (*Sampler, error) {
+	s := &Sampler{minVersion: minVersion}
+	for i, r := range rules {
+		if r.Accept < 0 || r.Accept > 1 {
+			return nil, fmt.Errorf("Throttle rule %d: accept must be in [0, 1]", i)
+		}
+
+		rule := sampleRule{
+			name:       r.Name,
+			conditions: map[string]*regexp.Regexp{},
+			accept:     r.Accept,
+		}
+
+		if len(rule.name) == 0 {
+			rule.name = fmt.Sprintf("rule_%d", i)
+		}
+
+		for field, pattern := range r.Conditions {
+			rx, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("Throttle rule %s: %s", rule.name, err.Error())
+			}
+			rule.conditions[field] = rx
+		}
+
+		s.rules = append(s.rules, rule)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+	"yabs/collector/cfg"
+	"yabs/common/format"
+)
+
+func TestSamplerAccept(t *testing.T) {
+	rules := []cfg.ThrottleRule{
+		{Name: "beta", Conditions: map[string]string{"version": `-beta$`}, Accept: 1},
+		{Name: "stable_windows", Conditions: map[string]string{"platform": `^windows$`, "kind": `^crash$`}, Accept: 0},
+		// shadowed by the first rule for beta builds
+		{Conditions: map[string]string{"platform": `^(windows|linux)$`}, Accept: 0},
+	}
+
+	sampler, err := NewSampler("5.10", rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		info     format.Info
+		accepted bool
+		reason   string
+	}{
+		{"below min version", format.Info{Version: "5.9", Platform: "windows"}, false, ReasonMinVersion},
+		{"pre-release of min version", format.Info{Version: "5.10-beta", Platform: "windows"}, false, ReasonMinVersion},
+		{"first rule wins", format.Info{Version: "5.12-beta", Platform: "windows"}, true, "beta"},
+		{"all conditions match", format.Info{Version: "5.12", Platform: "windows"}, false, "stable_windows"},
+		{"condition of other kind", format.Info{Version: "5.12", Platform: "windows", Kind: "hang"}, false, "rule_2"},
+		{"unnamed rule", format.Info{Version: "5.12", Platform: "linux"}, false, "rule_2"},
+		{"no rule matches", format.Info{Version: "6.0", Platform: "mac"}, true, ""},
+	}
+
+	for _, test := range tests {
+		accepted, reason := sampler.Accept(&test.info)
+		if accepted != test.accepted || reason != test.reason {
+			t.Errorf("%s: Accept = %t, %q, expected %t, %q", test.name, accepted, reason, test.accepted, test.reason)
+		}
+	}
+}
+
+func TestSamplerWithoutMinVersion(t *testing.T) {
+	sampler, err := NewSampler("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted, reason := sampler.Accept(&format.Info{Version: "0.1"})
+	if !accepted || len(reason) != 0 {
+		t.Errorf("Accept = %t, %q, expected accepted without rule", accepted, reason)
+	}
+}
+
+func TestNewSamplerInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule cfg.ThrottleRule
+	}{
+		{"accept above 1", cfg.ThrottleRule{Accept: 1.5}},
+		{"negative accept", cfg.ThrottleRule{Accept: -0.1}},
+		{"invalid regexp", cfg.ThrottleRule{Conditions: map[string]string{"version": `(`}, Accept: 1}},
+	}
+
+	for _, test := range tests {
+		_, err := NewSampler("", []cfg.ThrottleRule{test.rule})
+		if err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+}
@@ -1,6 +1,16 @@
 package base
 
+import (
+	"time"
+)
+
 type Cashe interface {
 	Get(key string) (string, error)
 	Set(key, value string) error
+	// Set the value which expires after ttl
+	SetTtl(key, value string, ttl time.Duration) error
+	// Increment the counter, a new counter expires after ttl
+	Incr(key string, ttl time.Duration) (int64, error)
+	// Remove the key, missing key isn't an error
+	Delete(key string) error
 }
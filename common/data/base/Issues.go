@@ -0,0 +1,83 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"yabs/common/issues"
+	"gopkg.in/olivere/elastic.v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const maxIssues = 1000
+
+// Known issue rules, rule id is the document id
+func (r *Repository) GetIssues() ([]*issues.Rule, error) {
+	searchResult, err := r.db.Search().
+		Index("breakpad").
+		Type("issue").
+		Query(elastic.NewMatchAllQuery()).
+		Sort("date_added", true).
+		Size(maxIssues).
+		Do(context.Background())
+
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var rules []*issues.Rule
+	for _, hit := range searchResult.Hits.Hits {
+		var rule issues.Rule
+		err := json.Unmarshal(*hit.Source, &rule)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"id":    hit.Id,
+			}).Error("Can't deserialize known issue")
+			continue
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, nil
+}
+
+// Create or replace the rule
+func (r *Repository) PutIssue(rule *issues.Rule) error {
+	_, err := r.db.
+		Index().
+		Index("breakpad").
+		Type("issue").
+		Id(rule.Id).
+		BodyJson(rule).
+		Refresh("true").
+		Do(context.Background())
+
+	if err != nil {
+		log.WithError(err).Error("Can't insert known issue")
+	}
+
+	return err
+}
+
+// Returns false if the rule isn't found
+func (r *Repository) RemoveIssue(id string) (bool, error) {
+	_, err := r.db.Delete().
+		Index("breakpad").
+		Type("issue").
+		Id(id).
+		Refresh("true").
+		Do(context.Background())
+
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return false, nil
+		}
+		log.WithError(err).Error("Can't remove known issue")
+		return false, err
+	}
+
+	return true, nil
+}
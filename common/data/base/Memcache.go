@@ -1,6 +1,7 @@
 package base
 
 import (
+	"time"
 	"github.com/bradfitz/gomemcache/memcache"
 )
 
@@ -22,6 +23,43 @@ func (m *Memcache) Set(key, value string) error {
 	return err
 }
 
+func (m *Memcache) SetTtl(key, value string, ttl time.Duration) error {
+	return m.client.Set(&memcache.Item{
+		Key:        key,
+		Value:      []byte(value),
+		Expiration: int32(ttl.Seconds()),
+	})
+}
+
+func (m *Memcache) Incr(key string, ttl time.Duration) (int64, error) {
+	v, err := m.client.Increment(key, 1)
+	if err == memcache.ErrCacheMiss {
+		err = m.client.Add(&memcache.Item{
+			Key:        key,
+			Value:      []byte("1"),
+			Expiration: int32(ttl.Seconds()),
+		})
+		if err == nil {
+			return 1, nil
+		}
+
+		// the counter was added by another client
+		if err == memcache.ErrNotStored {
+			v, err = m.client.Increment(key, 1)
+		}
+	}
+
+	return int64(v), err
+}
+
+func (m *Memcache) Delete(key string) error {
+	err := m.client.Delete(key)
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+	return err
+}
+
 func NewMemcache(servers []string) (*Memcache, error) {
 	return &Memcache{memcache.New(servers...)}, nil
 }
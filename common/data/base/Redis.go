@@ -22,6 +22,27 @@ func (r *Redis) Set(key, value string) error {
 	return r.client.Set(key, value, r.expiration).Err()
 }
 
+func (r *Redis) SetTtl(key, value string, ttl time.Duration) error {
+	return r.client.Set(key, value, ttl).Err()
+}
+
+func (r *Redis) Incr(key string, ttl time.Duration) (int64, error) {
+	v, err := r.client.Incr(key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if v == 1 {
+		err = r.client.Expire(key, ttl).Err()
+	}
+
+	return v, err
+}
+
+func (r *Redis) Delete(key string) error {
+	return r.client.Del(key).Err()
+}
+
 func NewRedis(address, password string) (*Redis, error) {
 	return &Redis{client: redis.NewClient(&redis.Options{
 		Addr:     address,
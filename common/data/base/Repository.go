@@ -22,6 +22,19 @@ type Symbol struct {
 	DebugId   string `json:"debugId"`
 	Platform  string `json:"platform"`
 	DataAdded string `json:"date_added"`
+	Md5       string `json:"md5,omitempty"`
+	Sha1      string `json:"sha1,omitempty"`
+}
+
+// Policy for a symbol upload whose debug id is already stored
+const (
+	DuplicateSkip    = "skip"
+	DuplicateReplace = "replace"
+	DuplicateFail    = "fail"
+)
+
+func IsDuplicatePolicy(p string) bool {
+	return p == DuplicateSkip || p == DuplicateReplace || p == DuplicateFail
 }
 
 func (r *Repository) putInCacheVersion2Id(v string, i uint64) {
@@ -66,6 +79,10 @@ func (r *Repository) IsExist(s *Symbol) (bool, error) {
 		return true, nil
 	}
 
+	if err != nil && !elastic.IsNotFound(err) {
+		return false, err
+	}
+
 	return false, nil
 }
 
@@ -112,6 +129,32 @@ func (r *Repository) GetSymbol(debugId string) (*Symbol, error) {
 	return nil, err
 }
 
+// Get the symbol document bypassing the cache, returns nil if it isn't found
+func (r *Repository) GetStoredSymbol(debugId string) (*Symbol, error) {
+	get, err := r.db.Get().
+		Index("breakpad").
+		Type("symbol").
+		Id(debugId).
+		Do(context.Background())
+
+	if elastic.IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var s Symbol
+	err = json.Unmarshal(*get.Source, &s)
+	if err != nil {
+		log.WithError(err).Error("Can't deserialize symbol")
+		return nil, err
+	}
+	s.DebugId = debugId
+	return &s, nil
+}
+
 func (r *Repository) GetSymbolForPlatform(platform, version string) (*Symbol, error) {
 	filter := elastic.NewBoolQuery().Must(elastic.NewTermQuery("platform", platform),
 		elastic.NewTermQuery("build", version))
@@ -142,13 +185,15 @@ func (r *Repository) GetSymbolForPlatform(platform, version string) (*Symbol, er
 }
 
 func (r *Repository) AddReport(report *minidump.Report) (string, error) {
+	return r.AddReportWithId(uuid.NewV4().String(), report)
+}
 
-	uuid := uuid.NewV4().String()
+func (r *Repository) AddReportWithId(id string, report *minidump.Report) (string, error) {
 	_, err := r.db.
 		Index().
 		Index("breakpad").
 		Type("crash").
-		Id(uuid).
+		Id(id).
 		BodyJson(report).
 		Refresh("true").
 		Do(context.Background())
@@ -157,7 +202,20 @@ func (r *Repository) AddReport(report *minidump.Report) (string, error) {
 		log.WithError(err).Error("Can't insert crash report")
 	}
 
-	return uuid, err
+	return id, err
+}
+
+// Count one more identical crash which wasn't stored because of throttling
+func (r *Repository) IncrementThrottled(id string) error {
+	script := elastic.NewScript("ctx._source.throttled_count = (ctx._source.throttled_count == null ? 0 : ctx._source.throttled_count) + 1")
+	_, err := r.db.Update().
+		Index("breakpad").
+		Type("crash").
+		Id(id).
+		Script(script).
+		RetryOnConflict(3).
+		Do(context.Background())
+	return err
 }
 
 func NewRepository(connectionUrl string, c Cashe) (*Repository, error) {
@@ -167,3 +225,38 @@ func NewRepository(connectionUrl string, c Cashe) (*Repository, error) {
 		cache: c,
 	}, err
 }
+
+// Delete crash documents, returns ids of crashes which are gone from the index.
+// Files of the other crashes must be kept, their documents still point to them
+func DeleteCrashes(db *elastic.Client, ids []string, refresh string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	bulk := db.Bulk().Refresh(refresh)
+	for _, id := range ids {
+		bulk.Add(elastic.NewBulkDeleteRequest().Index("breakpad").Type("crash").Id(id))
+	}
+
+	res, err := bulk.Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]string, 0, len(ids))
+	for _, item := range res.Deleted() {
+		// not found is deleted already
+		if item.Status == 200 || item.Status == 404 {
+			deleted = append(deleted, item.Id)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"id":     item.Id,
+			"status": item.Status,
+			"error":  item.Error,
+		}).Warning("Can't remove crash")
+	}
+
+	return deleted, nil
+}
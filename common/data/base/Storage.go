@@ -0,0 +1,134 @@
+package base
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+// Blob storage for raw files of crash reports
+type Storage interface {
+	// Move the local file into the storage, returns size of the blob
+	Put(key, src string) (int64, error)
+	Open(key string) (io.ReadCloser, error)
+	// Remove all blobs with the key prefix
+	Remove(prefix string) error
+}
+
+// Key of the crash report blob
+func CrashKey(id, name string) string {
+	return path.Join("crashes", id, name)
+}
+
+func CrashPrefix(id string) string {
+	return path.Join("crashes", id)
+}
+
+type FileStorage struct {
+	root string
+}
+
+func (f *FileStorage) Put(key, src string) (int64, error) {
+	target, err := f.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(target), 0777)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"key":   key,
+		}).Error("Can't create blob dir")
+		return 0, err
+	}
+
+	err = os.Rename(src, target)
+	if err != nil {
+		// the temporary dir can be on another device
+		err = copyFile(src, target)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"key":   key,
+				"path":  src,
+			}).Error("Can't put file in storage")
+			return 0, err
+		}
+		os.Remove(src)
+	}
+
+	stat, err := os.Stat(target)
+	if err != nil {
+		return 0, err
+	}
+
+	return stat.Size(), nil
+}
+
+func (f *FileStorage) Open(key string) (io.ReadCloser, error) {
+	target, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(target)
+}
+
+func (f *FileStorage) Remove(prefix string) error {
+	target, err := f.path(prefix)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(target)
+}
+
+func (f *FileStorage) path(key string) (string, error) {
+	target := filepath.Join(f.root, filepath.FromSlash(key))
+	if target == f.root || !strings.HasPrefix(target, f.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("Invalid storage key %q", key)
+	}
+
+	return target, nil
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+
+	return dst.Close()
+}
+
+func NewFileStorage(root string) (*FileStorage, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(root, 0777)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStorage{root: root}, nil
+}
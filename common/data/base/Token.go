@@ -0,0 +1,119 @@
+package base
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	log "github.com/sirupsen/logrus"
+)
+
+// Scopes of API tokens
+const (
+	ScopeSymbols = "symbols"
+	ScopeAdmin   = "admin"
+)
+
+const tokenPrefix = "yabs_"
+
+// API token, only hash of the secret is stored
+type Token struct {
+	Id        string   `json:"id"`
+	Name      string   `json:"name"`
+	Hash      string   `json:"hash"`
+	Scopes    []string `json:"scopes"`
+	Platforms []string `json:"platforms,omitempty"`
+	Builds    []string `json:"builds,omitempty"`
+	DateAdded string   `json:"date_added"`
+	Revoked   bool     `json:"revoked"`
+}
+
+// Check the scope of the token. Platform and build are matched
+// with patterns of the token, empty patterns match everything
+func (t *Token) Allow(scope, platform, build string) bool {
+	return t.HasScope(scope) &&
+		matchPatterns(t.Platforms, platform) &&
+		matchPatterns(t.Builds, build)
+}
+
+func (t *Token) HasScope(scope string) bool {
+	if t == nil || t.Revoked {
+		return false
+	}
+
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPatterns(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, p := range patterns {
+		if ok, err := path.Match(p, value); ok && err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func HashToken(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// Generate a new secret of the token
+func NewTokenSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return tokenPrefix + hex.EncodeToString(b), nil
+}
+
+// Token is stored with its hash as id
+func (r *Repository) AddToken(t *Token) error {
+	_, err := r.db.
+		Index().
+		Index("breakpad").
+		Type("token").
+		Id(t.Hash).
+		BodyJson(t).
+		Refresh("true").
+		Do(context.Background())
+
+	if err != nil {
+		log.WithError(err).Error("Can't insert token")
+	}
+
+	return err
+}
+
+func (r *Repository) GetToken(secret string) (*Token, error) {
+	get, err := r.db.Get().
+		Index("breakpad").
+		Type("token").
+		Id(HashToken(secret)).
+		Do(context.Background())
+
+	if err != nil {
+		return nil, err
+	}
+
+	var t Token
+	err = json.Unmarshal(*get.Source, &t)
+	if err != nil {
+		log.WithError(err).Error("Can't deserialize token")
+		return nil, err
+	}
+
+	return &t, nil
+}
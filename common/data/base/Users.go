@@ -0,0 +1,299 @@
+package base
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+	"yabs/common/format/minidump"
+	"gopkg.in/olivere/elastic.v5"
+	log "github.com/sirupsen/logrus"
+)
+
+// Operations with personal data of a user
+const (
+	AuditExport = "export"
+	AuditErase  = "erase"
+)
+
+const userScrollSize = 500
+
+// Reports without user id are stored with 0, they must never be exported or erased as one user
+var ErrAnonymousUser = errors.New("User id 0 belongs to anonymous reports")
+
+// Audit record of an operation with user data
+type Audit struct {
+	Operation string `json:"operation"`
+	UserId    uint64 `json:"user_id"`
+	Actor     string `json:"actor"`
+	Reports   int    `json:"reports"`
+	Error     string `json:"error,omitempty"`
+	DateAdded string `json:"date_added"`
+}
+
+// Summary of the exported archive, attachments which can't be read from the storage are listed as missing
+type ExportManifest struct {
+	UserId  uint64        `json:"user_id"`
+	Reports int           `json:"reports"`
+	Missing []MissingFile `json:"missing,omitempty"`
+}
+
+type MissingFile struct {
+	Report string `json:"report"`
+	Name   string `json:"name"`
+	Key    string `json:"key"`
+	Error  string `json:"error"`
+}
+
+type reportWalker func(id string, report *minidump.Report) error
+
+// Call walk for every crash report of the user
+func (r *Repository) eachUserReport(userId uint64, walk reportWalker) error {
+	if userId == 0 {
+		return ErrAnonymousUser
+	}
+
+	scroll := r.db.Scroll("breakpad").
+		Type("crash").
+		Query(elastic.NewTermQuery("user_id", userId)).
+		Size(userScrollSize)
+	defer scroll.Clear(context.Background())
+
+	for {
+		res, err := scroll.Do(context.Background())
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, hit := range res.Hits.Hits {
+			var report minidump.Report
+			err = json.Unmarshal(*hit.Source, &report)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"id":    hit.Id,
+				}).Error("Can't deserialize crash report")
+				return err
+			}
+
+			err = walk(hit.Id, &report)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Write reports, logs and attachments of the user into zip archive, returns count of reports.
+// The operation is recorded in the audit log on behalf of actor
+func (r *Repository) ExportUser(userId uint64, actor string, storage Storage, w io.Writer) (int, error) {
+	count, err := r.exportUser(userId, storage, w)
+	r.audit(AuditExport, userId, actor, count, err)
+	return count, err
+}
+
+// Remove reports of the user and their files, returns count of removed reports.
+// The operation is recorded in the audit log on behalf of actor
+func (r *Repository) EraseUser(userId uint64, actor string, storage Storage) (int, error) {
+	count, err := r.eraseUser(userId, storage)
+	r.audit(AuditErase, userId, actor, count, err)
+	return count, err
+}
+
+func (r *Repository) audit(operation string, userId uint64, actor string, count int, err error) {
+	a := &Audit{
+		Operation: operation,
+		UserId:    userId,
+		Actor:     actor,
+		Reports:   count,
+	}
+
+	if err != nil {
+		a.Error = err.Error()
+	}
+
+	if r.AddAudit(a) != nil {
+		log.WithFields(log.Fields{
+			"operation": operation,
+			"user id":   userId,
+			"actor":     actor,
+		}).Error("Operation with user data isn't audited")
+	}
+}
+
+func (r *Repository) exportUser(userId uint64, storage Storage, w io.Writer) (int, error) {
+	archive := zip.NewWriter(w)
+	manifest := &ExportManifest{UserId: userId}
+	count := 0
+
+	err := r.eachUserReport(userId, func(id string, report *minidump.Report) error {
+		count++
+
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		err = writeZipFile(archive, path.Join("reports", id+".json"), data)
+		if err != nil {
+			return err
+		}
+
+		if len(report.Log) != 0 {
+			err = writeZipFile(archive, path.Join("logs", id+".log"), []byte(report.Log))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, a := range report.Attachments {
+			src, err := storage.Open(a.Key)
+			if err != nil {
+				// the archive is still useful without the file, the manifest tells what's lost
+				log.WithFields(log.Fields{
+					"error":   err,
+					"user id": userId,
+					"key":     a.Key,
+				}).Warning("Can't open attachment of user report")
+				manifest.Missing = append(manifest.Missing, MissingFile{
+					Report: id,
+					Name:   a.Name,
+					Key:    a.Key,
+					Error:  err.Error(),
+				})
+				continue
+			}
+
+			err = copyZipFile(archive, path.Join("attachments", id, a.Name), src)
+			src.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"user id": userId,
+		}).Error("Can't export user data")
+		return count, err
+	}
+
+	manifest.Reports = count
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return count, err
+	}
+
+	err = writeZipFile(archive, "manifest.json", data)
+	if err != nil {
+		return count, err
+	}
+
+	return count, archive.Close()
+}
+
+func (r *Repository) eraseUser(userId uint64, storage Storage) (int, error) {
+	var ids []string
+	err := r.eachUserReport(userId, func(id string, report *minidump.Report) error {
+		ids = append(ids, id)
+		return nil
+	})
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"user id": userId,
+		}).Error("Can't find user reports")
+		return 0, err
+	}
+
+	// files are removed after the reports, so a failure never leaves reports without their files
+	count := 0
+	var failed error
+	for start := 0; start < len(ids); start += userScrollSize {
+		end := start + userScrollSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		deleted, err := DeleteCrashes(r.db, ids[start:end], "true")
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"user id": userId,
+			}).Error("Can't remove user reports")
+			return count, err
+		}
+
+		count += len(deleted)
+		for _, id := range deleted {
+			err = storage.Remove(CrashPrefix(id))
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":   err,
+					"user id": userId,
+					"id":      id,
+				}).Error("Can't remove files of user report")
+				failed = err
+			}
+		}
+
+		if len(deleted) != end-start {
+			return count, fmt.Errorf("%d of %d user reports aren't removed", end-start-len(deleted), end-start)
+		}
+	}
+
+	return count, failed
+}
+
+func (r *Repository) AddAudit(a *Audit) error {
+	if len(a.DateAdded) == 0 {
+		a.DateAdded = time.Now().Format(time.RFC3339)
+	}
+
+	_, err := r.db.
+		Index().
+		Index("breakpad").
+		Type("audit").
+		BodyJson(a).
+		Refresh("true").
+		Do(context.Background())
+
+	if err != nil {
+		log.WithError(err).Error("Can't insert audit record")
+	}
+
+	return err
+}
+
+func writeZipFile(archive *zip.Writer, name string, data []byte) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	return err
+}
+
+func copyZipFile(archive *zip.Writer, name string, src io.Reader) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, src)
+	return err
+}
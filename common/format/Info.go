@@ -1,9 +1,11 @@
 package format
 
 import (
+	"fmt"
 	"io/ioutil"
 	"encoding/json"
 	"strconv"
+	"strings"
 	"yabs/common/utils"
 	log "github.com/sirupsen/logrus"
 )
@@ -11,16 +13,61 @@ import (
 type GPUInfo struct {
 	Vendor   string `json:"vendor"`
 	Renderer string `json:"renderer"`
+	Driver   string `json:"driver,omitempty"`
+}
+
+// Kinds of reports, a report without kind is a crash
+const (
+	KindCrash  = "crash"
+	KindHang   = "hang"
+	KindOom    = "oom"
+	KindAssert = "assert"
+)
+
+func IsKind(kind string) bool {
+	return kind == KindCrash || kind == KindHang || kind == KindOom || kind == KindAssert
+}
+
+// Lower case kind, empty kind is a crash
+func NormalizeKind(kind string) string {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if len(kind) == 0 {
+		return KindCrash
+	}
+	return kind
+}
+
+// Breadcrumb is a timestamped trace of a client action before the crash
+type Breadcrumb struct {
+	Timestamp string            `json:"timestamp"`
+	Category  string            `json:"category"`
+	Message   string            `json:"message"`
+	Data      map[string]string `json:"data,omitempty"`
 }
 
 type Info struct {
-	Version  string `json:"version"`
-	Browser  string `json:"browser"`
-	Gpu      GPUInfo `json:"gpu"`
-	Platform string `json:"platform"`
-	Cpu      string `json:"cpu"`
-	Ram      string `json:"ram"`
-	UserId   string `json:"userid"`
+	Version      string `json:"version"`
+	Browser      string `json:"browser"`
+	Gpu          GPUInfo `json:"gpu"`
+	Platform     string `json:"platform"`
+	Cpu          string `json:"cpu"`
+	Ram          string `json:"ram"`
+	UserId       string `json:"userid"`
+	InstallId    string `json:"install_id,omitempty"`
+	Url          string `json:"url,omitempty"`
+	ErrorName    string `json:"error_name,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	Breadcrumbs  []Breadcrumb `json:"breadcrumbs,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	Kind         string `json:"kind,omitempty"`
+}
+
+// Limits bounds the client-defined part of the info
+type Limits struct {
+	MaxAnnotations    int
+	MaxAnnotationSize int
+	MaxBreadcrumbs    int
+	MaxBreadcrumbSize int
 }
 
 func InfoFromFile(path string) (info *Info, err error) {
@@ -55,3 +102,79 @@ func (i *Info) GetUserId() uint64 {
 	}
 	return userId
 }
+
+// Check that annotations and breadcrumbs are in the limits
+func (i *Info) CheckLimits(l Limits) error {
+	if !IsKind(NormalizeKind(i.Kind)) {
+		return fmt.Errorf("Unknown kind %s, need crash, hang, oom or assert", i.Kind)
+	}
+
+	if len(i.Annotations) > l.MaxAnnotations {
+		return fmt.Errorf("Too many annotations: %d, max %d", len(i.Annotations), l.MaxAnnotations)
+	}
+
+	for k, v := range i.Annotations {
+		if len(k) == 0 {
+			return fmt.Errorf("Annotation name can't be empty")
+		}
+
+		if len(k) > l.MaxAnnotationSize || len(v) > l.MaxAnnotationSize {
+			return fmt.Errorf("Annotation %.32q is too long, max %d bytes", k, l.MaxAnnotationSize)
+		}
+	}
+
+	if len(i.Breadcrumbs) > l.MaxBreadcrumbs {
+		return fmt.Errorf("Too many breadcrumbs: %d, max %d", len(i.Breadcrumbs), l.MaxBreadcrumbs)
+	}
+
+	for n, b := range i.Breadcrumbs {
+		size := len(b.Timestamp) + len(b.Category) + len(b.Message)
+		for k, v := range b.Data {
+			size += len(k) + len(v)
+		}
+
+		if size > l.MaxBreadcrumbSize {
+			return fmt.Errorf("Breadcrumb %d is too long, max %d bytes", n, l.MaxBreadcrumbSize)
+		}
+	}
+
+	return nil
+}
+
+// Value of the field by its json name, annotations are accessed as annotations.<key>
+func (i *Info) Field(name string) string {
+	switch name {
+	case "version":
+		return i.Version
+	case "browser":
+		return i.Browser
+	case "gpu.vendor":
+		return i.Gpu.Vendor
+	case "gpu.renderer":
+		return i.Gpu.Renderer
+	case "gpu.driver":
+		return i.Gpu.Driver
+	case "kind":
+		return NormalizeKind(i.Kind)
+	case "platform":
+		return i.Platform
+	case "cpu":
+		return i.Cpu
+	case "ram":
+		return i.Ram
+	case "userid":
+		return i.UserId
+	case "install_id":
+		return i.InstallId
+	case "url":
+		return i.Url
+	case "error_name":
+		return i.ErrorName
+	}
+
+	if strings.HasPrefix(name, "annotations.") {
+		return i.Annotations[strings.TrimPrefix(name, "annotations.")]
+	}
+
+	return ""
+}
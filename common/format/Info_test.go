@@ -0,0 +1,60 @@
+package format
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testLimits = Limits{
+	MaxAnnotations:    2,
+	MaxAnnotationSize: 8,
+	MaxBreadcrumbs:    2,
+	MaxBreadcrumbSize: 16,
+}
+
+func annotations(count int) map[string]string {
+	a := make(map[string]string)
+	for n := 0; n < count; n++ {
+		a["key"+strconv.Itoa(n)] = "value"
+	}
+	return a
+}
+
+func breadcrumbs(count int) []Breadcrumb {
+	b := make([]Breadcrumb, count)
+	for n := range b {
+		b[n] = Breadcrumb{Category: "ui", Message: "click"}
+	}
+	return b
+}
+
+func TestCheckLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		info  Info
+		valid bool
+	}{
+		{"empty", Info{}, true},
+		{"kind", Info{Kind: "Hang"}, true},
+		{"unknown kind", Info{Kind: "freeze"}, false},
+		{"annotations at limit", Info{Annotations: annotations(2)}, true},
+		{"too many annotations", Info{Annotations: annotations(3)}, false},
+		{"empty annotation name", Info{Annotations: map[string]string{"": "value"}}, false},
+		{"annotation name at limit", Info{Annotations: map[string]string{strings.Repeat("k", 8): "v"}}, true},
+		{"long annotation name", Info{Annotations: map[string]string{strings.Repeat("k", 9): "v"}}, false},
+		{"annotation value at limit", Info{Annotations: map[string]string{"k": strings.Repeat("v", 8)}}, true},
+		{"long annotation value", Info{Annotations: map[string]string{"k": strings.Repeat("v", 9)}}, false},
+		{"breadcrumbs at limit", Info{Breadcrumbs: breadcrumbs(2)}, true},
+		{"too many breadcrumbs", Info{Breadcrumbs: breadcrumbs(3)}, false},
+		{"breadcrumb at limit", Info{Breadcrumbs: []Breadcrumb{{Timestamp: "12", Category: "ui", Message: "click", Data: map[string]string{"id": "12345"}}}}, true},
+		{"long breadcrumb", Info{Breadcrumbs: []Breadcrumb{{Timestamp: "12", Category: "ui", Message: "click", Data: map[string]string{"id": "123456"}}}}, false},
+	}
+
+	for _, test := range tests {
+		err := test.info.CheckLimits(testLimits)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: CheckLimits = %v, expected valid %t", test.name, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package format
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Header of Breakpad symbol file: MODULE operatingsystem architecture id name
+type Module struct {
+	OS        string
+	Arch      string
+	DebugId   string
+	DebugFile string
+}
+
+func ParseModuleLine(line string) (*Module, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "MODULE" {
+		return nil, fmt.Errorf("Invalid MODULE line %.64q", line)
+	}
+
+	return &Module{
+		OS:        fields[1],
+		Arch:      fields[2],
+		DebugId:   fields[3],
+		DebugFile: strings.Join(fields[4:], " "),
+	}, nil
+}
+
+func ModuleFromFile(path string) (*Module, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && len(line) == 0 {
+		return nil, err
+	}
+
+	return ParseModuleLine(line)
+}
@@ -17,6 +17,8 @@ type ModuleInfo struct {
 	LoadedSymbols bool   `json:"loaded_symbols"`
 }
 
+// Rating of how likely the crash is an exploitable memory corruption:
+// none, low, medium or high
 type Sensitive struct {
 	Exploitability string `json:"exploitability"`
 }
@@ -4,11 +4,46 @@ import (
 	"yabs/common/format"
 )
 
+type Attachment struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+// What a processing stage did with the report
+type ProcessorNote struct {
+	Stage     string  `json:"stage"`
+	Action    string  `json:"action,omitempty"`
+	Note      string  `json:"note,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	Signature string  `json:"signature,omitempty"`
+	Duration  float64 `json:"duration_ms"`
+}
+
+// Known issue matched the report
+type KnownIssue struct {
+	Id      string `json:"id"`
+	Title   string `json:"title,omitempty"`
+	BugUrl  string `json:"bug_url"`
+	FixedIn string `json:"fixed_in,omitempty"`
+}
+
+// Loaded module which is neither ours nor of the OS
+type ThirdPartyModule struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Vendor  string `json:"vendor,omitempty"`
+	DebugId string `json:"debug_id,omitempty"`
+}
+
 type Report struct {
 	Context
 	UserId       uint64 `json:"user_id"`
+	InstallId    string `json:"install_id,omitempty"`
 	BuildVersion string `json:"build"`
 	Platform     string `json:"platform"`
+	Kind         string `json:"kind"`
 	Signature    string `json:"signature"`
 	Source       string `json:"source"`
 	CrashType    string `json:"crash_type"`
@@ -16,6 +51,44 @@ type Report struct {
 	DateAdded    string `json:"date_added"`
 	Gpu          format.GPUInfo `json:"gpu"`
 	Ram          string `json:"ram,omitempty"`
+	// page and error of web crashes
+	Url          string `json:"url,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	UserAgent    string `json:"user_agent,omitempty"`
+	Memory       *MemoryStatus `json:"memory,omitempty"`
+	Oom          string `json:"oom,omitempty"`
+	ThirdPartyModules []ThirdPartyModule `json:"third_party_modules,omitempty"`
 	RawCrash     string `json:"raw_dump,omitempty"`
 	Log          string `json:"raw_log,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	Breadcrumbs  []format.Breadcrumb `json:"breadcrumbs,omitempty"`
+	Attachments  []Attachment `json:"attachments,omitempty"`
+	Scrubbed     []string `json:"scrubbed,omitempty"`
+	ProcessorNotes []ProcessorNote `json:"processor_notes,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Priority     int    `json:"priority,omitempty"`
+	KnownIssue   *KnownIssue `json:"known_issue,omitempty"`
+	Sensitive    *Sensitive `json:"sensitive,omitempty"`
+	// identical crashes of the user which weren't stored because of throttling
+	ThrottledCount int64 `json:"throttled_count,omitempty"`
+}
+
+// Add the tag if the report doesn't have it yet
+func (r *Report) AddTag(tag string) {
+	for _, t := range r.Tags {
+		if t == tag {
+			return
+		}
+	}
+	r.Tags = append(r.Tags, tag)
+}
+
+// Add the name of the scrub rule if the report doesn't have it yet
+func (r *Report) AddScrubbed(rule string) {
+	for _, s := range r.Scrubbed {
+		if s == rule {
+			return
+		}
+	}
+	r.Scrubbed = append(r.Scrubbed, rule)
 }
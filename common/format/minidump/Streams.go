@@ -0,0 +1,307 @@
+package minidump
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const minidumpSignature = 0x504d444d // MDMP
+
+// Types of minidump streams with memory state
+const (
+	MemoryInfoListStream    = 16
+	SystemMemoryInfoStream  = 21
+	ProcessVmCountersStream = 22
+	LinuxMapsStream         = 0x47670009
+)
+
+const (
+	memCommit = 0x1000
+	memFree   = 0x10000
+	// PAGE_EXECUTE, PAGE_EXECUTE_READ, PAGE_EXECUTE_READWRITE, PAGE_EXECUTE_WRITECOPY
+	pageExecuteAny = 0x10 | 0x20 | 0x40 | 0x80
+	// bounds of corrupted dumps
+	maxStreams    = 4096
+	maxStreamSize = 64 << 20
+)
+
+var ErrNotMinidump = errors.New("File isn't a minidump")
+
+// Memory of the process and the system when the dump was written,
+// zero values are unknown. All sizes are in bytes
+type MemoryStatus struct {
+	PrivateUsage       uint64 `json:"private_usage,omitempty"`
+	FreeVirtual        uint64 `json:"free_virtual,omitempty"`
+	LargestFreeVirtual uint64 `json:"largest_free_virtual,omitempty"`
+	TotalPhysical      uint64 `json:"total_physical,omitempty"`
+	AvailablePhysical  uint64 `json:"available_physical,omitempty"`
+	CommitCharge       uint64 `json:"commit_charge,omitempty"`
+	CommitLimit        uint64 `json:"commit_limit,omitempty"`
+}
+
+func (m *MemoryStatus) Empty() bool {
+	return *m == MemoryStatus{}
+}
+
+// Region of the address space of the process
+type MemoryRegion struct {
+	Base       uint64
+	Size       uint64
+	Free       bool
+	Executable bool
+}
+
+type streamLocation struct {
+	size uint32
+	rva  uint32
+}
+
+// Read memory info list, system memory info and process VM counters streams of the minidump.
+// Streams which are absent are skipped
+func ReadMemoryStatus(path string) (*MemoryStatus, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	streams, err := readStreamDirectory(file)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MemoryStatus{}
+
+	if loc, ok := streams[MemoryInfoListStream]; ok {
+		err = readMemoryInfoList(file, loc, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if loc, ok := streams[SystemMemoryInfoStream]; ok {
+		err = readSystemMemoryInfo(file, loc, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if loc, ok := streams[ProcessVmCountersStream]; ok {
+		err = readProcessVmCounters(file, loc, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+func readStreamDirectory(r io.ReaderAt) (map[uint32]streamLocation, error) {
+	// MINIDUMP_HEADER
+	header := make([]byte, 32)
+	_, err := r.ReadAt(header, 0)
+	if err != nil {
+		return nil, ErrNotMinidump
+	}
+
+	if binary.LittleEndian.Uint32(header[0:]) != minidumpSignature {
+		return nil, ErrNotMinidump
+	}
+
+	count := binary.LittleEndian.Uint32(header[8:])
+	rva := binary.LittleEndian.Uint32(header[12:])
+	if count > maxStreams {
+		return nil, ErrNotMinidump
+	}
+
+	// MINIDUMP_DIRECTORY
+	directory := make([]byte, 12*int64(count))
+	_, err = r.ReadAt(directory, int64(rva))
+	if err != nil {
+		return nil, err
+	}
+
+	streams := map[uint32]streamLocation{}
+	for i := 0; i < int(count); i++ {
+		entry := directory[12*i:]
+		streams[binary.LittleEndian.Uint32(entry[0:])] = streamLocation{
+			size: binary.LittleEndian.Uint32(entry[4:]),
+			rva:  binary.LittleEndian.Uint32(entry[8:]),
+		}
+	}
+
+	return streams, nil
+}
+
+func readStream(r io.ReaderAt, loc streamLocation, minSize uint32) ([]byte, error) {
+	if loc.size < minSize || loc.size > maxStreamSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	data := make([]byte, loc.size)
+	_, err := r.ReadAt(data, int64(loc.rva))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Free regions of the address space
+func readMemoryInfoList(r io.ReaderAt, loc streamLocation, status *MemoryStatus) error {
+	regions, err := parseMemoryInfoList(r, loc)
+	if err != nil {
+		return err
+	}
+
+	for _, region := range regions {
+		if !region.Free {
+			continue
+		}
+
+		status.FreeVirtual += region.Size
+		if region.Size > status.LargestFreeVirtual {
+			status.LargestFreeVirtual = region.Size
+		}
+	}
+
+	return nil
+}
+
+// MINIDUMP_MEMORY_INFO_LIST of Windows dumps
+func parseMemoryInfoList(r io.ReaderAt, loc streamLocation) ([]MemoryRegion, error) {
+	data, err := readStream(r, loc, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	headerSize := uint64(binary.LittleEndian.Uint32(data[0:]))
+	entrySize := uint64(binary.LittleEndian.Uint32(data[4:]))
+	count := binary.LittleEndian.Uint64(data[8:])
+
+	size := uint64(len(data))
+	if entrySize < 48 || headerSize > size || count > (size-headerSize)/entrySize {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	regions := make([]MemoryRegion, 0, count)
+	for i := uint64(0); i < count; i++ {
+		entry := data[headerSize+i*entrySize:]
+		state := binary.LittleEndian.Uint32(entry[32:])
+		protect := binary.LittleEndian.Uint32(entry[36:])
+		regions = append(regions, MemoryRegion{
+			Base:       binary.LittleEndian.Uint64(entry[0:]),
+			Size:       binary.LittleEndian.Uint64(entry[24:]),
+			Free:       state == memFree,
+			Executable: state == memCommit && protect&pageExecuteAny != 0,
+		})
+	}
+
+	return regions, nil
+}
+
+// MD_LINUX_MAPS of Breakpad, the text of /proc/self/maps
+func parseLinuxMaps(r io.ReaderAt, loc streamLocation) ([]MemoryRegion, error) {
+	data, err := readStream(r, loc, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []MemoryRegion
+	for _, line := range strings.Split(string(data), "\n") {
+		// 7f0c1a000000-7f0c1a021000 r-xp 00000000 08:01 1234 /lib/libc.so
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		bounds := strings.SplitN(fields[0], "-", 2)
+		if len(bounds) != 2 || len(fields[1]) < 3 {
+			continue
+		}
+
+		start, err := strconv.ParseUint(bounds[0], 16, 64)
+		if err != nil {
+			continue
+		}
+
+		end, err := strconv.ParseUint(bounds[1], 16, 64)
+		if err != nil || end < start {
+			continue
+		}
+
+		regions = append(regions, MemoryRegion{
+			Base:       start,
+			Size:       end - start,
+			Executable: fields[1][2] == 'x',
+		})
+	}
+
+	return regions, nil
+}
+
+// Regions of the address space from the memory info list of Windows or the maps of Linux.
+// Returns nil if the dump has neither
+func ReadMemoryRegions(path string) ([]MemoryRegion, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	streams, err := readStreamDirectory(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if loc, ok := streams[MemoryInfoListStream]; ok {
+		return parseMemoryInfoList(file, loc)
+	}
+
+	if loc, ok := streams[LinuxMapsStream]; ok {
+		return parseLinuxMaps(file, loc)
+	}
+
+	return nil, nil
+}
+
+// Region which contains the address, nil if the address isn't mapped
+func FindRegion(regions []MemoryRegion, address uint64) *MemoryRegion {
+	for i := range regions {
+		r := &regions[i]
+		if !r.Free && address >= r.Base && address-r.Base < r.Size {
+			return r
+		}
+	}
+	return nil
+}
+
+// MINIDUMP_SYSTEM_MEMORY_INFO_1, physical memory and commit of the system
+func readSystemMemoryInfo(r io.ReaderAt, loc streamLocation, status *MemoryStatus) error {
+	data, err := readStream(r, loc, 148)
+	if err != nil {
+		return err
+	}
+
+	pageSize := uint64(binary.LittleEndian.Uint32(data[8:]))
+	status.TotalPhysical = uint64(binary.LittleEndian.Uint32(data[12:])) * pageSize
+	status.AvailablePhysical = binary.LittleEndian.Uint64(data[116:]) * pageSize
+	status.CommitCharge = binary.LittleEndian.Uint64(data[124:]) * pageSize
+	status.CommitLimit = binary.LittleEndian.Uint64(data[132:]) * pageSize
+
+	return nil
+}
+
+// MINIDUMP_PROCESS_VM_COUNTERS_1 and _2 have the same beginning
+func readProcessVmCounters(r io.ReaderAt, loc streamLocation, status *MemoryStatus) error {
+	data, err := readStream(r, loc, 80)
+	if err != nil {
+		return err
+	}
+
+	status.PrivateUsage = binary.LittleEndian.Uint64(data[72:])
+	return nil
+}
@@ -0,0 +1,155 @@
+package minidump
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Offsets in testdata/windows.dmp
+const (
+	fixtureStreamCount = 8
+	// memory info list is the first stream
+	fixtureEntrySize  = 68 + 4
+	fixtureEntryCount = 68 + 8
+)
+
+func TestReadMemoryStatus(t *testing.T) {
+	status, err := ReadMemoryStatus(filepath.Join("testdata", "windows.dmp"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := MemoryStatus{
+		PrivateUsage:       500 << 20,
+		FreeVirtual:        0x150000,
+		LargestFreeVirtual: 0x100000,
+		TotalPhysical:      1 << 30,
+		AvailablePhysical:  256 << 20,
+		CommitCharge:       512 << 20,
+		CommitLimit:        768 << 20,
+	}
+
+	if *status != expected {
+		t.Errorf("got %+v, expected %+v", *status, expected)
+	}
+}
+
+func TestReadMemoryStatusWithoutStreams(t *testing.T) {
+	status, err := ReadMemoryStatus(filepath.Join("testdata", "linux.dmp"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !status.Empty() {
+		t.Errorf("got %+v, expected empty status", *status)
+	}
+}
+
+func TestReadMemoryRegions(t *testing.T) {
+	tests := []struct {
+		dump       string
+		address    uint64
+		found      bool
+		executable bool
+	}{
+		{"windows.dmp", 0x10000, true, true},
+		{"windows.dmp", 0x10fff, true, true},
+		{"windows.dmp", 0x11000, false, false},
+		{"windows.dmp", 0x21000, true, false},
+		// free and reserved regions aren't mapped or executable
+		{"windows.dmp", 0x30000, false, false},
+		{"windows.dmp", 0x300000, true, false},
+		{"windows.dmp", 0x41414141, false, false},
+		{"linux.dmp", 0x400000, true, true},
+		{"linux.dmp", 0x651000, true, false},
+		{"linux.dmp", 0x7f0c1a000100, true, true},
+		{"linux.dmp", 0x7ffd2c000010, true, false},
+		{"linux.dmp", 0x0, false, false},
+	}
+
+	for _, test := range tests {
+		regions, err := ReadMemoryRegions(filepath.Join("testdata", test.dump))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.dump, err)
+		}
+
+		region := FindRegion(regions, test.address)
+		if (region != nil) != test.found {
+			t.Errorf("%s at %#x: found %t, expected %t", test.dump, test.address, region != nil, test.found)
+			continue
+		}
+
+		if region != nil && region.Executable != test.executable {
+			t.Errorf("%s at %#x: executable %t, expected %t", test.dump, test.address, region.Executable, test.executable)
+		}
+	}
+}
+
+func TestReadCorruptedMinidump(t *testing.T) {
+	fixture, err := ioutil.ReadFile(filepath.Join("testdata", "windows.dmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+	}{
+		{"signature", func(data []byte) []byte {
+			copy(data, "XXXX")
+			return data
+		}},
+		{"short header", func(data []byte) []byte {
+			return data[:16]
+		}},
+		{"too many streams", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[fixtureStreamCount:], maxStreams+1)
+			return data
+		}},
+		{"truncated stream", func(data []byte) []byte {
+			return data[:100]
+		}},
+		{"small entry", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[fixtureEntrySize:], 8)
+			return data
+		}},
+		{"entry count overflow", func(data []byte) []byte {
+			binary.LittleEndian.PutUint64(data[fixtureEntryCount:], 1<<62)
+			return data
+		}},
+	}
+
+	for _, test := range tests {
+		data := append([]byte(nil), fixture...)
+		path := writeTemp(t, test.corrupt(data))
+
+		_, err := ReadMemoryStatus(path)
+		if err == nil {
+			t.Errorf("%s: ReadMemoryStatus expected error", test.name)
+		}
+
+		_, err = ReadMemoryRegions(path)
+		if err == nil {
+			t.Errorf("%s: ReadMemoryRegions expected error", test.name)
+		}
+
+		os.Remove(path)
+	}
+}
+
+func writeTemp(t *testing.T, data []byte) string {
+	file, err := ioutil.TempFile("", "minidump_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
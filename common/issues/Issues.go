@@ -0,0 +1,215 @@
+package issues
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+	"yabs/common/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tag of reports matched by a known issue rule
+const Tag = "known_issue"
+
+// Rule of a known issue. Empty conditions match everything,
+// regexps are matched against signature, module file names, GPU and OS version,
+// builds are compared as versions and both bounds are inclusive
+type Rule struct {
+	Id          string `json:"id"`
+	Title       string `json:"title"`
+	BugUrl      string `json:"bug_url"`
+	FixedIn     string `json:"fixed_in,omitempty"`
+	Platform    string `json:"platform,omitempty"`
+	Signature   string `json:"signature_rx,omitempty"`
+	Module      string `json:"module,omitempty"`
+	GpuVendor   string `json:"gpu_vendor,omitempty"`
+	GpuRenderer string `json:"gpu_renderer,omitempty"`
+	OsVersion   string `json:"os_version,omitempty"`
+	MinBuild    string `json:"min_build,omitempty"`
+	MaxBuild    string `json:"max_build,omitempty"`
+	DateAdded   string `json:"date_added"`
+	Disabled    bool   `json:"disabled"`
+
+	signature   *regexp.Regexp
+	module      *regexp.Regexp
+	gpuVendor   *regexp.Regexp
+	gpuRenderer *regexp.Regexp
+	osVersion   *regexp.Regexp
+}
+
+// Check the rule and compile its regexps
+func (r *Rule) Compile() error {
+	if len(r.Id) == 0 {
+		return fmt.Errorf("Rule id is required")
+	}
+
+	if len(r.BugUrl) == 0 {
+		return fmt.Errorf("Rule %s: bug_url is required", r.Id)
+	}
+
+	var err error
+	compile := func(field, pattern string) *regexp.Regexp {
+		if len(pattern) == 0 || err != nil {
+			return nil
+		}
+
+		rx, e := regexp.Compile(pattern)
+		if e != nil {
+			err = fmt.Errorf("Rule %s: invalid %s: %s", r.Id, field, e.Error())
+		}
+		return rx
+	}
+
+	r.signature = compile("signature_rx", r.Signature)
+	r.module = compile("module", r.Module)
+	r.gpuVendor = compile("gpu_vendor", r.GpuVendor)
+	r.gpuRenderer = compile("gpu_renderer", r.GpuRenderer)
+	r.osVersion = compile("os_version", r.OsVersion)
+
+	return err
+}
+
+// Rule can be matched by info only, e.g. when the crash is received
+func (r *Rule) infoOnly() bool {
+	return r.signature == nil && r.module == nil && r.osVersion == nil
+}
+
+func (r *Rule) matchInfo(platform, build string, gpu format.GPUInfo) bool {
+	if r.Disabled {
+		return false
+	}
+
+	if len(r.Platform) != 0 && r.Platform != platform {
+		return false
+	}
+
+	if len(r.MinBuild) != 0 && utils.CompareVersions(build, r.MinBuild) < 0 {
+		return false
+	}
+
+	if len(r.MaxBuild) != 0 && utils.CompareVersions(build, r.MaxBuild) > 0 {
+		return false
+	}
+
+	return matchRx(r.gpuVendor, gpu.Vendor) && matchRx(r.gpuRenderer, gpu.Renderer)
+}
+
+// Match the processed report
+func (r *Rule) Match(report *minidump.Report) bool {
+	if !r.matchInfo(report.Platform, report.BuildVersion, report.Gpu) {
+		return false
+	}
+
+	if !matchRx(r.signature, report.Signature) || !matchRx(r.osVersion, report.SystemInfo.OS_Version) {
+		return false
+	}
+
+	if r.module == nil {
+		return true
+	}
+
+	for _, m := range report.Modules {
+		if r.module.MatchString(m.File) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchRx(rx *regexp.Regexp, value string) bool {
+	return rx == nil || rx.MatchString(value)
+}
+
+// Known issue saved in the report
+func (r *Rule) Issue() *minidump.KnownIssue {
+	return &minidump.KnownIssue{
+		Id:      r.Id,
+		Title:   r.Title,
+		BugUrl:  r.BugUrl,
+		FixedIn: r.FixedIn,
+	}
+}
+
+// Rules loaded from the repository, they are reloaded when ttl expires.
+// The caller which finds them expired reloads them, others use the old rules meanwhile
+type Set struct {
+	mutex   sync.Mutex
+	load    func() ([]*Rule, error)
+	ttl     time.Duration
+	loaded  time.Time
+	loading bool
+	rules   []*Rule
+}
+
+func NewSet(load func() ([]*Rule, error), ttl time.Duration) *Set {
+	return &Set{load: load, ttl: ttl}
+}
+
+func (s *Set) current() []*Rule {
+	s.mutex.Lock()
+	if s.loading || time.Since(s.loaded) < s.ttl {
+		rules := s.rules
+		s.mutex.Unlock()
+		return rules
+	}
+	s.loading = true
+	s.mutex.Unlock()
+
+	rules, err := s.reload()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// on error the old rules are used till the next attempt
+	s.loading = false
+	s.loaded = time.Now()
+	if err == nil {
+		s.rules = rules
+	}
+	return s.rules
+}
+
+// Load and compile the rules without holding the mutex
+func (s *Set) reload() ([]*Rule, error) {
+	rules, err := s.load()
+	if err != nil {
+		log.WithError(err).Error("Can't load known issues")
+		return nil, err
+	}
+
+	var valid []*Rule
+	for _, r := range rules {
+		err := r.Compile()
+		if err != nil {
+			log.WithError(err).Warning("Skip invalid known issue")
+			continue
+		}
+		valid = append(valid, r)
+	}
+
+	return valid, nil
+}
+
+// The first rule matched the report, nil if the issue isn't known
+func (s *Set) Match(report *minidump.Report) *Rule {
+	for _, r := range s.current() {
+		if r.Match(report) {
+			return r
+		}
+	}
+	return nil
+}
+
+// The first rule which can be matched by info only, they are checked when the crash is received
+func (s *Set) MatchInfo(info *format.Info) *Rule {
+	for _, r := range s.current() {
+		if r.infoOnly() && r.matchInfo(info.Platform, info.Version, info.Gpu) {
+			return r
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package task
 import (
 	"encoding/json"
 	"time"
+	"yabs/common/format/minidump"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -20,11 +21,12 @@ type Symbol struct {
 }
 
 type Dump struct {
-	Type uint   `json:"type"`
-	Path string `json:"minidump"`
-	Info string `json:"info"`
-	Log  string `json:"log"`
-	Time string `json:"time,omitempty"`
+	Type        uint   `json:"type"`
+	Path        string `json:"minidump"`
+	Info        string `json:"info"`
+	Log         string `json:"log"`
+	Time        string `json:"time,omitempty"`
+	Attachments map[string]string `json:"attachments,omitempty"`
 }
 
 type WebDump struct {
@@ -34,6 +36,14 @@ type WebDump struct {
 	Time string `json:"time,omitempty"`
 }
 
+// Result of a crash task for the collector which waits for it
+type Result struct {
+	Id         string `json:"id,omitempty"`
+	Signature  string `json:"signature,omitempty"`
+	KnownIssue *minidump.KnownIssue `json:"known_issue,omitempty"`
+	Dropped    bool   `json:"dropped,omitempty"`
+}
+
 func FromJson(data []byte) interface{} {
 	type Test struct {
 		Type uint `json:"type"`
@@ -93,12 +103,13 @@ func CreateSymbolsTask(symbols []string, info string) *Symbol {
 		Info: info}
 }
 
-func CreateDumpTask(dump, info, log string) *Dump {
+func CreateDumpTask(dump, info, log string, attachments map[string]string) *Dump {
 	return &Dump{Type: PROCESS_DUMP,
 		Path: dump,
 		Info: info,
 		Log: log,
-		Time: getTimeStamp()}
+		Time: getTimeStamp(),
+		Attachments: attachments}
 }
 
 func CreateWebDumpTask(dump, info string) *WebDump {
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+func FileMd5Hash(path string) (string, error) {
+	return fileHash(path, md5.New())
+}
+
+func FileSha1Hash(path string) (string, error) {
+	return fileHash(path, sha1.New())
+}
+
+// Upper case hex digest of the file content
+func fileHash(path string, h hash.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return strings.ToUpper(fmt.Sprintf("%x", h.Sum(nil))), nil
+}
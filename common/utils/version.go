@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Compare dotted versions like 5.12.1, returns -1, 0 or 1. Missing parts are 0,
+// so 5.12 equals 5.12.0, and a part with a suffix is a pre-release: 5.10-beta < 5.10
+func CompareVersions(a, b string) int {
+	aParts := strings.Split(Trim(a), ".")
+	bParts := strings.Split(Trim(b), ".")
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aPart, bPart := "0", "0"
+		if i < len(aParts) && len(aParts[i]) != 0 {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) && len(bParts[i]) != 0 {
+			bPart = bParts[i]
+		}
+
+		if r := comparePart(aPart, bPart); r != 0 {
+			return r
+		}
+	}
+
+	return 0
+}
+
+// Compare numeric prefixes of parts as numbers and suffixes as strings
+func comparePart(a, b string) int {
+	aNum, aSuffix := splitPart(a)
+	bNum, bSuffix := splitPart(b)
+
+	if aNum < bNum {
+		return -1
+	} else if aNum > bNum {
+		return 1
+	}
+
+	// a part without suffix is a release, it's greater than any pre-release
+	if len(aSuffix) == 0 && len(bSuffix) != 0 {
+		return 1
+	} else if len(aSuffix) != 0 && len(bSuffix) == 0 {
+		return -1
+	}
+	return strings.Compare(aSuffix, bSuffix)
+}
+
+// Split the part into the number and the suffix: 9-beta is 9 and -beta
+func splitPart(part string) (uint64, string) {
+	digits := 0
+	for digits < len(part) && part[digits] >= '0' && part[digits] <= '9' {
+		digits++
+	}
+
+	num, err := strconv.ParseUint(part[:digits], 10, 64)
+	if err != nil {
+		return 0, part
+	}
+	return num, part[digits:]
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"5.12.1", "5.12.1", 0},
+		{"5.12", "5.12.0", 0},
+		{"5.12.0.0", "5.12", 0},
+		{"5.12", "5.12.1", -1},
+		{"5.9", "5.10", -1},
+		{"5.10", "5.9", 1},
+		{"5.9-beta", "5.10", -1},
+		{"5.10-beta", "5.9", 1},
+		{"5.10-beta", "5.10", -1},
+		{"5.10", "5.10-beta", 1},
+		{"5.10-alpha", "5.10-beta", -1},
+		{"5.10rc1", "5.10rc2", -1},
+		{"5.12\x00", "5.12", 0},
+		{"", "0", 0},
+		{"", "1.0", -1},
+		{"dev", "1.0", -1},
+	}
+
+	for _, test := range tests {
+		if actual := CompareVersions(test.a, test.b); actual != test.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", test.a, test.b, actual, test.expected)
+		}
+	}
+}
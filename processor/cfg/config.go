@@ -4,21 +4,34 @@ import (
 	"os"
 	"errors"
 	"encoding/json"
+	"yabs/common/format"
 	log "github.com/sirupsen/logrus"
 )
 
 type Config interface {
 	SymbolsPath() string
+	// attachments of crashes aren't stored if it's empty
+	StoragePath() string
 	RabbitServer() string
 	RabbitQueue() string
 	RabbitPostExchange() string
 	RabbitPostType() string
+	// kinds of reports for post-processing, it counts crash rates so hangs are excluded by default
+	RabbitPostKinds() []string
+	// queue of crashes whose clients wait for the result, it's consumed first
+	RabbitFastQueue() string
 	ElasticUrl() string
 	Memcache() []string
 	RedisAddres() string
 	RedisPassword() string
 	LogLevel() string
 	WebBlackListSignaturs() []string
+	ThrottleEnable() bool
+	ThrottleMaxPerHour() int
+	// ordered stages per platform: win, mac, lin, web or default
+	Pipelines() map[string][]StageCfg
+	// seconds for all stages of a report
+	PipelineTimeout() int
 }
 
 var GlobalConfig Config
@@ -44,5 +57,49 @@ func FromJson(pathTo string) (Config, error) {
 		return nil, errors.New("symbols_pathname can't is empty")
 	}
 
+	if jconf.Throttle == nil {
+		jconf.Throttle = &ThrottleCfg{}
+	}
+
+	if jconf.Rabbit != nil && jconf.Rabbit.PostKinds == nil {
+		jconf.Rabbit.PostKinds = []string{format.KindCrash, format.KindOom, format.KindAssert}
+	}
+
+	if jconf.PipelineTimeoutSec <= 0 {
+		jconf.PipelineTimeoutSec = 30
+	}
+
+	if jconf.PipelineStages == nil {
+		jconf.PipelineStages, err = defaultPipelines(jconf.WebBListSignaturs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &jconf, nil
+}
+
+// Pipelines which were used before they became configurable
+func defaultPipelines(webBlackList []string) (map[string][]StageCfg, error) {
+	rxOptions, err := json.Marshal(map[string][]string{
+		"regexps": webBlackList,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]StageCfg{
+		"default": {
+			{Stage: "signature_and_source"},
+			{Stage: "stack_unfolding"},
+			{Stage: "oom"},
+			{Stage: "exploitability"},
+			{Stage: "third_party"},
+			{Stage: "known_issues"},
+		},
+		"web": {
+			{Stage: "rx", Options: rxOptions},
+			{Stage: "known_issues"},
+		},
+	}, nil
 }
\ No newline at end of file
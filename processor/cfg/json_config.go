@@ -1,10 +1,16 @@
 package cfg
 
+import (
+	"encoding/json"
+)
+
 type RabbitCfg struct {
 	Server   string `json:"server"`
 	Queue    string `json:"queue"`
 	Exchange string `json:"post-exchange"`
 	Type     string `json:"post-type"`
+	FastQueue string `json:"fast-queue"`
+	PostKinds []string `json:"post-kinds"`
 }
 
 type RedisCfg struct {
@@ -21,19 +27,38 @@ type LogCfg struct {
 	Level string `json:"level"`
 }
 
+type ThrottleCfg struct {
+	Enable     bool `json:"enable"`
+	MaxPerHour int  `json:"max_per_hour"`
+}
+
+// Stage of a pipeline, options depend on the stage
+type StageCfg struct {
+	Stage   string          `json:"stage"`
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
 type JsonConfig struct {
 	SymbolsPathName   string     `json:"symbols_pathname"`
+	StoragePathName   string     `json:"storage_pathname"`
 	Rabbit            *RabbitCfg `json:"rabbit_cfg"`
 	Cache             *CacheCfg  `json:"cache"`
 	Elastic           string     `json:"elastic"`
 	Log               *LogCfg    `json:"log"`
 	WebBListSignaturs []string   `json:"web_blacklist_signaturs"`
+	Throttle          *ThrottleCfg `json:"throttle"`
+	PipelineStages    map[string][]StageCfg `json:"pipelines"`
+	PipelineTimeoutSec int        `json:"pipeline_timeout"`
 }
 
 func (cfg *JsonConfig) SymbolsPath() string {
 	return cfg.SymbolsPathName
 }
 
+func (cfg *JsonConfig) StoragePath() string {
+	return cfg.StoragePathName
+}
+
 func (cfg *JsonConfig) RabbitServer() string {
 	return cfg.Rabbit.Server
 }
@@ -42,6 +67,14 @@ func (cfg *JsonConfig) RabbitQueue() string {
 	return cfg.Rabbit.Queue
 }
 
+func (cfg *JsonConfig) RabbitPostKinds() []string {
+	return cfg.Rabbit.PostKinds
+}
+
+func (cfg *JsonConfig) RabbitFastQueue() string {
+	return cfg.Rabbit.FastQueue
+}
+
 func (cfg *JsonConfig) ElasticUrl() string {
 	return cfg.Elastic
 }
@@ -73,3 +106,19 @@ func (cfg *JsonConfig) RabbitPostType() string {
 func (cfg *JsonConfig) LogLevel() string {
 	return cfg.Log.Level
 }
+
+func (cfg *JsonConfig) ThrottleEnable() bool {
+	return cfg.Throttle.Enable
+}
+
+func (cfg *JsonConfig) ThrottleMaxPerHour() int {
+	return cfg.Throttle.MaxPerHour
+}
+
+func (cfg *JsonConfig) Pipelines() map[string][]StageCfg {
+	return cfg.PipelineStages
+}
+
+func (cfg *JsonConfig) PipelineTimeout() int {
+	return cfg.PipelineTimeoutSec
+}
@@ -0,0 +1,180 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+)
+
+// Ratings of exploitability, from a crash which can't be exploited to a likely memory corruption
+const (
+	ExploitabilityNone   = "none"
+	ExploitabilityLow    = "low"
+	ExploitabilityMedium = "medium"
+	ExploitabilityHigh   = "high"
+)
+
+// Exception types of Windows, signals of Linux and exceptions of macOS as the stackwalker names them
+var (
+	// the process detected corruption of its stack or heap
+	corruptionTypes = regexp.MustCompile(`(?i)STACK_BUFFER_OVERRUN|0xc0000409|HEAP_CORRUPTION|0xc0000374`)
+	// frames of glibc and the CRT which abort on detected corruption
+	corruptionFrames = regexp.MustCompile(`__stack_chk_fail|__fortify_fail|__chk_fail|malloc_printerr|RtlReportFatalFailure|RtlpHeapHandleError`)
+	accessTypes      = regexp.MustCompile(`(?i)ACCESS_VIOLATION|IN_PAGE_ERROR|SIGSEGV|SIGBUS|EXC_BAD_ACCESS`)
+	instructionTypes = regexp.MustCompile(`(?i)ILLEGAL_INSTRUCTION|PRIV_INSTRUCTION|SIGILL|EXC_BAD_INSTRUCTION`)
+	benignTypes      = regexp.MustCompile(`(?i)BREAKPOINT|SINGLE_STEP|DIVIDE_BY_ZERO|INT_OVERFLOW|FLT_|STACK_OVERFLOW|0xe06d7363|SIGFPE|SIGTRAP|SIGABRT|EXC_ARITHMETIC|EXC_BREAKPOINT|EXC_CRASH|DUMP_REQUESTED`)
+)
+
+// Registers of the instruction pointer on x86, x86-64 and ARM
+var instructionPointers = []string{"rip", "eip", "pc"}
+
+// Rates exploitability of native crashes by the rules of Breakpad's exploitability_win and
+// exploitability_linux: exception type, access address vs. instruction pointer, whether the
+// fault is a write and whether the instruction pointer is in executable memory
+type Exploitability struct {
+}
+
+func init() {
+	RegisterV2("exploitability", func(options json.RawMessage) (StageV2, error) {
+		return &Exploitability{}, nil
+	})
+}
+
+func (e *Exploitability) Run(ctx context.Context, report *minidump.Report, info *format.Info, raw *Raw) Result {
+	var regions []minidump.MemoryRegion
+	var readErr error
+	if raw != nil && len(raw.DumpPath) != 0 {
+		regions, readErr = minidump.ReadMemoryRegions(raw.DumpPath)
+	}
+
+	rating, reason := rateExploitability(report, regions)
+	report.Sensitive = &minidump.Sensitive{Exploitability: rating}
+
+	return Result{Action: Continue, Note: rating + ", " + reason, Err: readErr}
+}
+
+// Rating and the reason of it
+func rateExploitability(report *minidump.Report, regions []minidump.MemoryRegion) (string, string) {
+	if report.Kind != format.KindCrash && len(report.Kind) != 0 {
+		return ExploitabilityNone, "kind " + report.Kind
+	}
+
+	crashType := report.CrashType
+	if len(crashType) == 0 {
+		return ExploitabilityNone, "no exception"
+	}
+
+	if corruptionTypes.MatchString(crashType) {
+		return ExploitabilityHigh, "corruption detected, " + crashType
+	}
+
+	frames := report.CrashingThread.Frames
+	if len(frames) > oomTopFrames {
+		frames = frames[:oomTopFrames]
+	}
+	for _, f := range frames {
+		if corruptionFrames.MatchString(f.Function) {
+			return ExploitabilityHigh, "corruption detected, frame " + f.Function
+		}
+	}
+
+	ip, hasIp := instructionPointer(report)
+	if hasIp && ip >= nullAddress {
+		if regions != nil {
+			region := minidump.FindRegion(regions, ip)
+			if region == nil || !region.Executable {
+				return ExploitabilityHigh, "instruction pointer in non-executable memory"
+			}
+		} else if len(report.Modules) != 0 && !inModule(report.Modules, ip) {
+			return ExploitabilityHigh, "instruction pointer outside of modules"
+		}
+	}
+
+	if benignTypes.MatchString(crashType) {
+		return ExploitabilityNone, crashType
+	}
+
+	if instructionTypes.MatchString(crashType) {
+		return ExploitabilityMedium, crashType
+	}
+
+	if !accessTypes.MatchString(crashType) {
+		return ExploitabilityLow, "unknown exception " + crashType
+	}
+
+	address, err := parseAddress(report.Address)
+	if err != nil {
+		return ExploitabilityLow, "unknown address"
+	}
+	nearNull := address < nullAddress
+
+	upper := strings.ToUpper(crashType)
+	switch {
+	case strings.HasSuffix(upper, "_EXEC") || hasIp && address == ip:
+		if nearNull {
+			// call of a null function pointer
+			return ExploitabilityLow, "execution near null"
+		}
+		return ExploitabilityHigh, "execution of data"
+	case strings.HasSuffix(upper, "_WRITE"):
+		if nearNull {
+			return ExploitabilityLow, "write near null"
+		}
+		return ExploitabilityHigh, "write to invalid address"
+	case strings.HasSuffix(upper, "_READ"):
+		if nearNull {
+			return ExploitabilityNone, "read near null"
+		}
+		return ExploitabilityMedium, "read of invalid address"
+	}
+
+	// signals don't tell reads from writes
+	if nearNull {
+		return ExploitabilityLow, "access near null"
+	}
+	return ExploitabilityMedium, "access of invalid address"
+}
+
+// Instruction pointer from registers of the crashing frame
+func instructionPointer(report *minidump.Report) (uint64, bool) {
+	if len(report.CrashingThread.Frames) == 0 {
+		return 0, false
+	}
+
+	registers := report.CrashingThread.Frames[0].Registers
+	for _, name := range instructionPointers {
+		if value, ok := registers[name]; ok {
+			ip, err := parseAddress(value)
+			return ip, err == nil
+		}
+	}
+	return 0, false
+}
+
+func inModule(modules []minidump.ModuleInfo, address uint64) bool {
+	for _, m := range modules {
+		base, err := parseAddress(m.Address)
+		if err != nil {
+			continue
+		}
+
+		end, err := parseAddress(m.EndAddr)
+		if err != nil {
+			continue
+		}
+
+		if address >= base && address < end {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAddress(value string) (uint64, error) {
+	value = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "0x")
+	return strconv.ParseUint(value, 16, 64)
+}
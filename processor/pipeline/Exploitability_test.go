@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"testing"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+)
+
+func exploitabilityReport(crashType, address, ip string, functions ...string) *minidump.Report {
+	report := &minidump.Report{CrashType: crashType, Address: address}
+	report.Modules = []minidump.ModuleInfo{{Address: "0x400000", EndAddr: "0x500000"}}
+
+	frame := minidump.TrheadFrame{Function: "main"}
+	if len(ip) != 0 {
+		frame.Registers = map[string]string{"rip": ip}
+	}
+	for _, f := range functions {
+		report.CrashingThread.Frames = append(report.CrashingThread.Frames, minidump.TrheadFrame{Function: f})
+	}
+	report.CrashingThread.Frames = append([]minidump.TrheadFrame{frame}, report.CrashingThread.Frames...)
+	return report
+}
+
+func TestRateExploitability(t *testing.T) {
+	hang := exploitabilityReport("EXCEPTION_ACCESS_VIOLATION_WRITE", "0x41414141", "0x401000")
+	hang.Kind = format.KindHang
+
+	noModules := exploitabilityReport("SIGSEGV", "0x41414141", "0x7f0000001000")
+	noModules.Modules = nil
+
+	regions := []minidump.MemoryRegion{
+		{Base: 0x400000, Size: 0x100000, Executable: true},
+		{Base: 0x600000, Size: 0x100000, Executable: false},
+	}
+
+	tests := []struct {
+		name     string
+		report   *minidump.Report
+		regions  []minidump.MemoryRegion
+		expected string
+	}{
+		{"hang", hang, nil, ExploitabilityNone},
+		{"no exception", exploitabilityReport("", "", "0x401000"), nil, ExploitabilityNone},
+		{"stack buffer overrun", exploitabilityReport("STATUS_STACK_BUFFER_OVERRUN", "0x0", "0x401000"), nil, ExploitabilityHigh},
+		{"heap corruption code", exploitabilityReport("0xc0000374", "0x0", "0x401000"), nil, ExploitabilityHigh},
+		{"corruption frame", exploitabilityReport("SIGABRT", "0x0", "0x401000", "abort", "__stack_chk_fail"), nil, ExploitabilityHigh},
+		{"ip outside modules", exploitabilityReport("SIGSEGV", "0x41414141", "0x41414141"), nil, ExploitabilityHigh},
+		{"ip without modules", noModules, nil, ExploitabilityMedium},
+		{"ip in non-executable region", exploitabilityReport("SIGSEGV", "0x8", "0x600100"), regions, ExploitabilityHigh},
+		{"ip in unmapped region", exploitabilityReport("SIGSEGV", "0x8", "0x900000"), regions, ExploitabilityHigh},
+		{"ip in executable region", exploitabilityReport("SIGSEGV", "0x8", "0x401000"), regions, ExploitabilityLow},
+		{"breakpoint", exploitabilityReport("EXCEPTION_BREAKPOINT", "0x401000", "0x401000"), nil, ExploitabilityNone},
+		{"sigabrt", exploitabilityReport("SIGABRT", "0x0", "0x401000"), nil, ExploitabilityNone},
+		{"illegal instruction", exploitabilityReport("SIGILL", "0x401000", "0x401000"), nil, ExploitabilityMedium},
+		{"unknown exception", exploitabilityReport("EXCEPTION_GUARD_PAGE", "0x0", "0x401000"), nil, ExploitabilityLow},
+		{"unknown address", exploitabilityReport("SIGSEGV", "", "0x401000"), nil, ExploitabilityLow},
+		{"exec near null", exploitabilityReport("EXCEPTION_ACCESS_VIOLATION_EXEC", "0x0", ""), nil, ExploitabilityLow},
+		{"exec of data", exploitabilityReport("EXCEPTION_ACCESS_VIOLATION_EXEC", "0x41414141", ""), nil, ExploitabilityHigh},
+		{"address is ip", exploitabilityReport("SIGSEGV", "0x401000", "0x401000"), nil, ExploitabilityHigh},
+		{"write near null", exploitabilityReport("EXCEPTION_ACCESS_VIOLATION_WRITE", "0x10", "0x401000"), nil, ExploitabilityLow},
+		{"write", exploitabilityReport("EXCEPTION_ACCESS_VIOLATION_WRITE", "0x41414141", "0x401000"), nil, ExploitabilityHigh},
+		{"read near null", exploitabilityReport("EXCEPTION_ACCESS_VIOLATION_READ", "0x10", "0x401000"), nil, ExploitabilityNone},
+		{"read", exploitabilityReport("EXCEPTION_ACCESS_VIOLATION_READ", "0x41414141", "0x401000"), nil, ExploitabilityMedium},
+		{"signal near null", exploitabilityReport("SIGSEGV", "0xffff", "0x401000"), nil, ExploitabilityLow},
+		{"signal", exploitabilityReport("SIGBUS", "0x10000", "0x401000"), nil, ExploitabilityMedium},
+	}
+
+	for _, test := range tests {
+		rating, reason := rateExploitability(test.report, test.regions)
+		if rating != test.expected {
+			t.Errorf("%s: rating %s (%s), expected %s", test.name, rating, reason, test.expected)
+		}
+	}
+}
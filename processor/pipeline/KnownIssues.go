@@ -0,0 +1,43 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+	"yabs/common/issues"
+)
+
+// Rules of known issues from the repository, set by the processor before pipelines are built
+var KnownIssues *issues.Set
+
+// Marks reports of known issues with the tag and the bug link
+type KnownIssueStage struct {
+	rules *issues.Set
+}
+
+func init() {
+	RegisterV2("known_issues", func(options json.RawMessage) (StageV2, error) {
+		if KnownIssues == nil {
+			return nil, errors.New("Known issues aren't available")
+		}
+		return &KnownIssueStage{KnownIssues}, nil
+	})
+}
+
+func (s *KnownIssueStage) Run(ctx context.Context, report *minidump.Report, info *format.Info, raw *Raw) Result {
+	rule := s.rules.Match(report)
+	if rule == nil {
+		return Result{Action: Continue}
+	}
+
+	report.KnownIssue = rule.Issue()
+	report.AddTag(issues.Tag)
+
+	return Result{
+		Action: Continue,
+		Note:   fmt.Sprintf("%s: %s", rule.Id, rule.BugUrl),
+	}
+}
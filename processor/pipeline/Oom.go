@@ -0,0 +1,203 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+)
+
+// Classes of out-of-memory crashes. Small allocation failed because memory is exhausted,
+// large one failed while there is still memory. Unknown if the dump has no memory state
+const (
+	OomSmall   = "small"
+	OomLarge   = "large"
+	OomUnknown = "unknown"
+)
+
+const (
+	defaultOomFrames = `bad_alloc|OutOfMemory|OnNoMemory|out_of_memory|NS_ABORT_OOM|mozalloc_handle_oom`
+	// exception codes of allocators and NTSTATUS of exhausted memory
+	oomCrashTypes = `(?i)0xe0000008|0xc0000017|0xc000012d|STATUS_NO_MEMORY|STATUS_COMMITMENT_LIMIT`
+	// crash of dereferencing a failed allocation
+	nullCrashTypes = `(?i)ACCESS_VIOLATION|SIGSEGV|SIGBUS`
+	nullAddress    = 0x10000
+	// frames of the top of the stack checked for allocation failures
+	oomTopFrames = 10
+)
+
+// Client annotations with the size of the failed allocation
+var oomSizeAnnotations = []string{"OOMAllocationSize", "oom_allocation_size"}
+
+// Classifies out-of-memory crashes by exception codes, frames and memory state of the dump.
+// Signatures of OOM crashes are replaced by "OOM | <class>"
+type OomClassifier struct {
+	frames          *regexp.Regexp
+	crashTypes      *regexp.Regexp
+	nullCrashTypes  *regexp.Regexp
+	largeAllocation uint64
+	lowMemory       uint64
+	usage           float64
+}
+
+func init() {
+	RegisterV2("oom", func(options json.RawMessage) (StageV2, error) {
+		opts := struct {
+			Frames          string  `json:"frames"`
+			LargeAllocation uint64  `json:"large_allocation"`
+			LowMemory       uint64  `json:"low_memory"`
+			Usage           float64 `json:"usage"`
+		}{defaultOomFrames, 256 << 10, 64 << 20, 0.9}
+
+		err := decodeOptions(options, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		frames, err := regexp.Compile(opts.Frames)
+		if err != nil {
+			return nil, err
+		}
+
+		return &OomClassifier{
+			frames:          frames,
+			crashTypes:      regexp.MustCompile(oomCrashTypes),
+			nullCrashTypes:  regexp.MustCompile(nullCrashTypes),
+			largeAllocation: opts.LargeAllocation,
+			lowMemory:       opts.LowMemory,
+			usage:           opts.Usage,
+		}, nil
+	})
+}
+
+func (o *OomClassifier) Run(ctx context.Context, report *minidump.Report, info *format.Info, raw *Raw) Result {
+	var memory *minidump.MemoryStatus
+	var readErr error
+	if raw != nil && len(raw.DumpPath) != 0 {
+		memory, readErr = minidump.ReadMemoryStatus(raw.DumpPath)
+		if memory != nil && !memory.Empty() {
+			report.Memory = memory
+		}
+	}
+
+	ram := parseRam(report.Ram)
+	exhausted := memory != nil && o.exhausted(memory, ram)
+
+	reason := o.detect(report, exhausted)
+	if len(reason) == 0 {
+		return Result{Action: Continue, Err: readErr}
+	}
+
+	class := OomUnknown
+	if size, ok := allocationSize(report.Annotations); ok {
+		class = OomSmall
+		if size >= o.largeAllocation {
+			class = OomLarge
+		}
+	} else if exhausted {
+		class = OomSmall
+	} else if memory != nil && !memory.Empty() {
+		class = OomLarge
+	}
+
+	note := fmt.Sprintf("%s, was %q", reason, report.Signature)
+
+	report.Kind = format.KindOom
+	report.Oom = class
+	report.Signature = "OOM | " + class
+
+	return Result{Action: Continue, Note: note, Err: readErr}
+}
+
+// Reason why the report is OOM, empty if it isn't
+func (o *OomClassifier) detect(report *minidump.Report, exhausted bool) string {
+	if report.Kind == format.KindOom {
+		return "kind"
+	}
+
+	if o.crashTypes.MatchString(report.CrashType) {
+		return "crash type " + report.CrashType
+	}
+
+	frames := signatureFrames(report)
+	if len(frames) > oomTopFrames {
+		frames = frames[:oomTopFrames]
+	}
+	for _, f := range frames {
+		if o.frames.MatchString(f.Function) {
+			return "frame " + f.Function
+		}
+	}
+
+	if exhausted && o.nullCrashTypes.MatchString(report.CrashType) {
+		address, err := strconv.ParseUint(strings.TrimPrefix(report.Address, "0x"), 16, 64)
+		if err == nil && address < nullAddress {
+			return "null address on exhausted memory"
+		}
+	}
+
+	return ""
+}
+
+// Memory is exhausted if commit, address space or client RAM is nearly used up
+func (o *OomClassifier) exhausted(m *minidump.MemoryStatus, ram uint64) bool {
+	if m.CommitLimit != 0 && m.CommitCharge+o.lowMemory >= m.CommitLimit {
+		return true
+	}
+
+	if m.FreeVirtual != 0 && m.LargestFreeVirtual < o.lowMemory {
+		return true
+	}
+
+	total := m.TotalPhysical
+	if total == 0 {
+		total = ram
+	}
+
+	return total != 0 && m.PrivateUsage != 0 && float64(m.PrivateUsage) >= o.usage*float64(total)
+}
+
+func allocationSize(annotations map[string]string) (uint64, bool) {
+	for _, name := range oomSizeAnnotations {
+		if v, ok := annotations[name]; ok {
+			size, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+			if err == nil {
+				return size, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// RAM of the client in bytes: "8 GB", "512MB" or a number of megabytes, 0 if unknown
+func parseRam(ram string) uint64 {
+	ram = strings.ToUpper(strings.TrimSpace(ram))
+	units := []struct {
+		suffix string
+		size   uint64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	unit := uint64(1 << 20)
+	for _, u := range units {
+		if strings.HasSuffix(ram, u.suffix) {
+			ram = strings.TrimSpace(strings.TrimSuffix(ram, u.suffix))
+			unit = u.size
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(ram, 64)
+	if err != nil || value <= 0 {
+		return 0
+	}
+	return uint64(value * float64(unit))
+}
@@ -0,0 +1,161 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+)
+
+var windowsDump = filepath.Join("..", "..", "common", "format", "minidump", "testdata", "windows.dmp")
+
+func newOomClassifier(t *testing.T) *OomClassifier {
+	stage, err := NewStage("oom", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stage.(*OomClassifier)
+}
+
+func TestParseRam(t *testing.T) {
+	tests := []struct {
+		ram      string
+		expected uint64
+	}{
+		{"8 GB", 8 << 30},
+		{"512MB", 512 << 20},
+		{"1.5gb", 3 << 29},
+		{"64 KB", 64 << 10},
+		{"100 B", 100},
+		{"2048", 2048 << 20},
+		{"", 0},
+		{"unknown", 0},
+		{"-1 GB", 0},
+	}
+
+	for _, test := range tests {
+		if actual := parseRam(test.ram); actual != test.expected {
+			t.Errorf("parseRam(%q) = %d, expected %d", test.ram, actual, test.expected)
+		}
+	}
+}
+
+func TestAllocationSize(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		size        uint64
+		ok          bool
+	}{
+		{map[string]string{"OOMAllocationSize": "1024"}, 1024, true},
+		{map[string]string{"oom_allocation_size": " 4096 "}, 4096, true},
+		{map[string]string{"OOMAllocationSize": "big", "oom_allocation_size": "8"}, 8, true},
+		{map[string]string{"OOMAllocationSize": "-1"}, 0, false},
+		{map[string]string{}, 0, false},
+		{nil, 0, false},
+	}
+
+	for _, test := range tests {
+		size, ok := allocationSize(test.annotations)
+		if size != test.size || ok != test.ok {
+			t.Errorf("allocationSize(%v) = %d, %t, expected %d, %t", test.annotations, size, ok, test.size, test.ok)
+		}
+	}
+}
+
+func TestOomExhausted(t *testing.T) {
+	o := newOomClassifier(t)
+
+	tests := []struct {
+		name     string
+		memory   minidump.MemoryStatus
+		ram      uint64
+		expected bool
+	}{
+		{"empty", minidump.MemoryStatus{}, 0, false},
+		{"commit within low memory of limit", minidump.MemoryStatus{CommitCharge: 960 << 20, CommitLimit: 1 << 30}, 0, true},
+		{"commit below limit", minidump.MemoryStatus{CommitCharge: 512 << 20, CommitLimit: 1 << 30}, 0, false},
+		{"fragmented address space", minidump.MemoryStatus{FreeVirtual: 1 << 30, LargestFreeVirtual: 1 << 20}, 0, true},
+		{"free address space", minidump.MemoryStatus{FreeVirtual: 1 << 30, LargestFreeVirtual: 512 << 20}, 0, false},
+		{"private usage of physical", minidump.MemoryStatus{PrivateUsage: 950 << 20, TotalPhysical: 1 << 30}, 0, true},
+		{"private usage of client ram", minidump.MemoryStatus{PrivateUsage: 950 << 20}, 1 << 30, true},
+		{"low private usage", minidump.MemoryStatus{PrivateUsage: 100 << 20}, 1 << 30, false},
+		{"unknown total", minidump.MemoryStatus{PrivateUsage: 950 << 20}, 0, false},
+	}
+
+	for _, test := range tests {
+		if actual := o.exhausted(&test.memory, test.ram); actual != test.expected {
+			t.Errorf("%s: exhausted = %t, expected %t", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestOomDetect(t *testing.T) {
+	o := newOomClassifier(t)
+
+	frames := func(functions ...string) minidump.CrashingThread {
+		thread := minidump.CrashingThread{}
+		for _, f := range functions {
+			thread.Frames = append(thread.Frames, minidump.TrheadFrame{Function: f})
+		}
+		return thread
+	}
+
+	deep := make([]string, oomTopFrames)
+	for i := range deep {
+		deep[i] = "main"
+	}
+
+	tests := []struct {
+		name      string
+		report    minidump.Report
+		exhausted bool
+		detected  bool
+	}{
+		{"kind", minidump.Report{Kind: format.KindOom}, false, true},
+		{"crash type", minidump.Report{CrashType: "0xe0000008"}, false, true},
+		{"status", minidump.Report{CrashType: "STATUS_NO_MEMORY"}, false, true},
+		{"frame", minidump.Report{Context: minidump.Context{CrashingThread: frames("abort", "std::__throw_bad_alloc")}}, false, true},
+		{"frame below top", minidump.Report{Context: minidump.Context{CrashingThread: frames(append(deep, "mozalloc_handle_oom")...)}}, false, false},
+		{"null on exhausted", minidump.Report{CrashType: "EXCEPTION_ACCESS_VIOLATION_READ", Address: "0x8"}, true, true},
+		{"null on free memory", minidump.Report{CrashType: "EXCEPTION_ACCESS_VIOLATION_READ", Address: "0x8"}, false, false},
+		{"wild on exhausted", minidump.Report{CrashType: "SIGSEGV", Address: "0x41414141"}, true, false},
+		{"crash", minidump.Report{CrashType: "SIGSEGV", Address: "0x0", Context: minidump.Context{CrashingThread: frames("main")}}, false, false},
+	}
+
+	for _, test := range tests {
+		reason := o.detect(&test.report, test.exhausted)
+		if (len(reason) != 0) != test.detected {
+			t.Errorf("%s: detect = %q, expected detected %t", test.name, reason, test.detected)
+		}
+	}
+}
+
+func TestOomRun(t *testing.T) {
+	o := newOomClassifier(t)
+
+	tests := []struct {
+		name      string
+		report    minidump.Report
+		raw       *Raw
+		signature string
+	}{
+		{"small allocation", minidump.Report{CrashType: "0xe0000008", Annotations: map[string]string{"OOMAllocationSize": "64"}}, nil, "OOM | small"},
+		{"large allocation", minidump.Report{CrashType: "0xe0000008", Annotations: map[string]string{"OOMAllocationSize": "1073741824"}}, nil, "OOM | large"},
+		{"no memory state", minidump.Report{CrashType: "0xe0000008"}, nil, "OOM | unknown"},
+		// largest free region of the fixture is below low memory
+		{"exhausted dump", minidump.Report{CrashType: "EXCEPTION_ACCESS_VIOLATION_WRITE", Address: "0x0"}, &Raw{DumpPath: windowsDump}, "OOM | small"},
+		{"not oom", minidump.Report{CrashType: "SIGSEGV", Address: "0x41414141", Signature: "main"}, &Raw{DumpPath: windowsDump}, "main"},
+	}
+
+	for _, test := range tests {
+		result := o.Run(context.Background(), &test.report, &format.Info{}, test.raw)
+		if result.Err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, result.Err)
+		}
+
+		if test.report.Signature != test.signature {
+			t.Errorf("%s: signature %q, expected %q", test.name, test.report.Signature, test.signature)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+)
+
+// Pipeline of a platform which isn't configured
+const DefaultPipeline = "default"
+
+// Creates a stage from its options in the config
+type Factory func(options json.RawMessage) (Stage, error)
+
+type FactoryV2 func(options json.RawMessage) (StageV2, error)
+
+var registry = map[string]FactoryV2{}
+
+// Register the stage implementation, it's called from init of the stage
+func Register(name string, factory Factory) {
+	RegisterV2(name, func(options json.RawMessage) (StageV2, error) {
+		stage, err := factory(options)
+		if err != nil {
+			return nil, err
+		}
+		return Adapt(stage), nil
+	})
+}
+
+func RegisterV2(name string, factory FactoryV2) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("Stage %s is already registered", name))
+	}
+	registry[name] = factory
+}
+
+func NewStage(name string, options json.RawMessage) (StageV2, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown stage %s", name)
+	}
+
+	stage, err := factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid options of stage %s: %s", name, err.Error())
+	}
+
+	return stage, nil
+}
+
+type Step struct {
+	Name  string
+	Stage StageV2
+}
+
+// Ordered stages
+type Pipeline []Step
+
+// Run stages until one of them stops the pipeline or ctx is done.
+// Stages of the first version are skipped once the signature is final.
+// Decisions and timing of stages are saved in the processor notes of the report.
+// Returns false if the report must be dropped
+func (p Pipeline) Process(ctx context.Context, report *minidump.Report, info *format.Info, raw *Raw) bool {
+	final := false
+	for _, step := range p {
+		if _, ok := step.Stage.(*stageAdapter); ok && final {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			report.ProcessorNotes = append(report.ProcessorNotes, minidump.ProcessorNote{
+				Stage: step.Name,
+				Error: "skipped: " + ctx.Err().Error(),
+			})
+			return true
+		}
+
+		signature := report.Signature
+		start := time.Now()
+		res := step.Stage.Run(ctx, report, info, raw)
+
+		note := minidump.ProcessorNote{
+			Stage:    step.Name,
+			Action:   res.Action.String(),
+			Note:     res.Note,
+			Duration: time.Since(start).Seconds() * 1000,
+		}
+
+		if res.Err != nil {
+			note.Error = res.Err.Error()
+		}
+
+		if report.Signature != signature {
+			note.Signature = report.Signature
+		}
+
+		report.ProcessorNotes = append(report.ProcessorNotes, note)
+
+		switch res.Action {
+		case Final:
+			final = true
+		case Stop:
+			return true
+		case Drop:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Pipelines per platform, they are replaced all at once on reload
+type Pipelines struct {
+	mutex      sync.RWMutex
+	byPlatform map[string]Pipeline
+}
+
+func NewPipelines(byPlatform map[string]Pipeline) *Pipelines {
+	return &Pipelines{byPlatform: byPlatform}
+}
+
+func (p *Pipelines) Set(byPlatform map[string]Pipeline) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.byPlatform = byPlatform
+}
+
+// Pipeline of the platform or the default one
+func (p *Pipelines) For(platform string) Pipeline {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if pline, ok := p.byPlatform[platform]; ok {
+		return pline
+	}
+	return p.byPlatform[DefaultPipeline]
+}
+
+func decodeOptions(options json.RawMessage, v interface{}) error {
+	if len(options) == 0 {
+		return nil
+	}
+	return json.Unmarshal(options, v)
+}
@@ -1,6 +1,7 @@
 package pipeline
 
 import (
+	"encoding/json"
 	"regexp"
 	"yabs/common/format"
 	"yabs/common/format/minidump"
@@ -13,14 +14,29 @@ type Rx struct {
 	Regexps []*regexp.Regexp
 }
 
+func init() {
+	Register("rx", func(options json.RawMessage) (Stage, error) {
+		var opts struct {
+			Regexps []string `json:"regexps"`
+		}
+
+		err := decodeOptions(options, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewRx(opts.Regexps), nil
+	})
+}
+
 func (r *Rx) Process(report *minidump.Report, info *format.Info) bool {
 	if len(r.Regexps) == 0 {
 		// to next stage
 		return false
 	}
 
-	frames := report.CrashingThread.Frames
-	if len(report.CrashingThread.Frames) == 0 {
+	frames := signatureFrames(report)
+	if len(frames) == 0 {
 		// go to next stage
 		return false
 	}
@@ -34,14 +50,12 @@ func (r *Rx) Process(report *minidump.Report, info *format.Info) bool {
 			}
 		}
 		if !isMatch {
-			report.Signature = frame.Function
+			report.Signature = kindSignature(report, frame.Function)
 			return true
 		}
 	}
 
-	if len(frames) > 0 {
-		report.Signature = frames[0].Function
-	}
+	report.Signature = kindSignature(report, frames[0].Function)
 
 	return true
 }
@@ -0,0 +1,323 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+	"yabs/common/utils"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// Relative paths of scripts are resolved against the directory of the processor config
+var ScriptDir string
+
+const (
+	scriptStateKey = "state"
+	scriptMaxSteps = 10000000
+)
+
+// Starlark script with function process(report, info).
+// Report and info are read only values, the script changes the report with
+// set_signature, add_tag, drop and set_priority
+type Script struct {
+	name    string
+	process starlark.Callable
+}
+
+// Changes of the report made by one run of the script
+type scriptState struct {
+	report  *minidump.Report
+	drop    bool
+	actions []string
+}
+
+var scriptBuiltins starlark.StringDict
+
+func init() {
+	scriptBuiltins = starlark.StringDict{
+		"set_signature":    starlark.NewBuiltin("set_signature", scriptSetSignature),
+		"add_tag":          starlark.NewBuiltin("add_tag", scriptAddTag),
+		"drop":             starlark.NewBuiltin("drop", scriptDrop),
+		"set_priority":     starlark.NewBuiltin("set_priority", scriptSetPriority),
+		"compare_versions": starlark.NewBuiltin("compare_versions", scriptCompareVersions),
+	}
+
+	RegisterV2("script", func(options json.RawMessage) (StageV2, error) {
+		var opts struct {
+			File string `json:"file"`
+		}
+
+		err := decodeOptions(options, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(opts.File) == 0 {
+			return nil, errors.New("Option file is required")
+		}
+
+		path := opts.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(ScriptDir, path)
+		}
+
+		return LoadScript(path)
+	})
+}
+
+func LoadScript(path string) (*Script, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &starlark.Thread{Name: path}
+	globals, err := starlark.ExecFile(thread, path, src, scriptBuiltins)
+	if err != nil {
+		return nil, err
+	}
+
+	// module values are shared by all reports, so process can't change them
+	globals.Freeze()
+
+	process, ok := globals["process"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("Script %s must define process(report, info)", path)
+	}
+
+	return &Script{
+		name:    filepath.Base(path),
+		process: process,
+	}, nil
+}
+
+func (s *Script) Run(ctx context.Context, report *minidump.Report, info *format.Info, raw *Raw) Result {
+	state := &scriptState{report: report}
+	thread := &starlark.Thread{Name: s.name}
+	thread.SetLocal(scriptStateKey, state)
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	args := starlark.Tuple{reportValue(report), infoValue(info)}
+	_, err := starlark.Call(thread, s.process, args, nil)
+
+	res := Result{
+		Action: Continue,
+		Note:   strings.Join(state.actions, ", "),
+		Err:    err,
+	}
+
+	if err == nil && state.drop {
+		res.Action = Drop
+	}
+
+	return res
+}
+
+func stateOf(thread *starlark.Thread, fn *starlark.Builtin) (*scriptState, error) {
+	state, ok := thread.Local(scriptStateKey).(*scriptState)
+	if !ok {
+		return nil, fmt.Errorf("%s: can be called only from process", fn.Name())
+	}
+	return state, nil
+}
+
+func scriptSetSignature(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var signature string
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "signature", &signature)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := stateOf(thread, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	state.report.Signature = signature
+	state.actions = append(state.actions, fmt.Sprintf("set_signature(%q)", signature))
+	return starlark.None, nil
+}
+
+func scriptAddTag(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var tag string
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "tag", &tag)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := stateOf(thread, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	state.report.AddTag(tag)
+	state.actions = append(state.actions, fmt.Sprintf("add_tag(%q)", tag))
+	return starlark.None, nil
+}
+
+func scriptDrop(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var reason string
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "reason?", &reason)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := stateOf(thread, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	state.drop = true
+	state.actions = append(state.actions, fmt.Sprintf("drop(%q)", reason))
+	return starlark.None, nil
+}
+
+func scriptSetPriority(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var priority int
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "priority", &priority)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := stateOf(thread, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	state.report.Priority = priority
+	state.actions = append(state.actions, fmt.Sprintf("set_priority(%d)", priority))
+	return starlark.None, nil
+}
+
+// compare_versions("5.12.1", "5.9") returns -1, 0 or 1
+func scriptCompareVersions(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var a, b string
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "a", &a, "b", &b)
+	if err != nil {
+		return nil, err
+	}
+
+	return starlark.MakeInt(utils.CompareVersions(a, b)), nil
+}
+
+// Frames are the ones of the signature, e.g. of the main thread for hangs
+func reportValue(r *minidump.Report) starlark.Value {
+	signature := signatureFrames(r)
+	frames := make([]starlark.Value, 0, len(signature))
+	for _, f := range signature {
+		frames = append(frames, starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"function": starlark.String(f.Function),
+			"module":   starlark.String(f.Module),
+			"file":     starlark.String(f.File),
+			"line":     starlark.MakeInt(int(f.Line)),
+		}))
+	}
+
+	modules := make([]starlark.Value, 0, len(r.Modules))
+	for _, m := range r.Modules {
+		modules = append(modules, starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"file":       starlark.String(m.File),
+			"debug_file": starlark.String(m.DebugFile),
+			"debug_id":   starlark.String(m.DebugId),
+			"version":    starlark.String(m.Version),
+		}))
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"signature":   starlark.String(r.Signature),
+		"source":      starlark.String(r.Source),
+		"platform":    starlark.String(r.Platform),
+		"kind":        starlark.String(r.Kind),
+		"oom":         starlark.String(r.Oom),
+		"sensitive":   sensitiveValue(r.Sensitive),
+		"build":       starlark.String(r.BuildVersion),
+		"crash_type":  starlark.String(r.CrashType),
+		"address":     starlark.String(r.Address),
+		"user_id":     starlark.MakeUint64(r.UserId),
+		"install_id":  starlark.String(r.InstallId),
+		"ram":         starlark.String(r.Ram),
+		"os":          starlark.String(r.SystemInfo.OS),
+		"os_version":  starlark.String(r.SystemInfo.OS_Version),
+		"cpu_arch":    starlark.String(r.SystemInfo.CpuArch),
+		"gpu":         gpuValue(r.Gpu),
+		"annotations": stringsDict(r.Annotations),
+		"tags":        stringsList(r.Tags),
+		"priority":    starlark.MakeInt(r.Priority),
+		"frames":      starlark.NewList(frames),
+		"modules":     starlark.NewList(modules),
+	})
+}
+
+func sensitiveValue(s *minidump.Sensitive) starlark.Value {
+	exploitability := ""
+	if s != nil {
+		exploitability = s.Exploitability
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"exploitability": starlark.String(exploitability),
+	})
+}
+
+func infoValue(i *format.Info) starlark.Value {
+	if i == nil {
+		return starlark.None
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"version":       starlark.String(i.Version),
+		"browser":       starlark.String(i.Browser),
+		"platform":      starlark.String(i.Platform),
+		"cpu":           starlark.String(i.Cpu),
+		"ram":           starlark.String(i.Ram),
+		"userid":        starlark.String(i.UserId),
+		"install_id":    starlark.String(i.InstallId),
+		"url":           starlark.String(i.Url),
+		"error_name":    starlark.String(i.ErrorName),
+		"error_message": starlark.String(i.ErrorMessage),
+		"gpu":           gpuValue(i.Gpu),
+		"annotations":   stringsDict(i.Annotations),
+		"kind":          starlark.String(format.NormalizeKind(i.Kind)),
+	})
+}
+
+func gpuValue(g format.GPUInfo) starlark.Value {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"vendor":   starlark.String(g.Vendor),
+		"renderer": starlark.String(g.Renderer),
+		"driver":   starlark.String(g.Driver),
+	})
+}
+
+func stringsDict(m map[string]string) starlark.Value {
+	d := starlark.NewDict(len(m))
+	for k, v := range m {
+		d.SetKey(starlark.String(k), starlark.String(v))
+	}
+	return d
+}
+
+func stringsList(l []string) starlark.Value {
+	values := make([]starlark.Value, 0, len(l))
+	for _, s := range l {
+		values = append(values, starlark.String(s))
+	}
+	return starlark.NewList(values)
+}
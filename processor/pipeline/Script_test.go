@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+)
+
+func loadTestScript(t *testing.T) *Script {
+	script, err := LoadScript(filepath.Join("testdata", "test.star"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func frames(functions ...string) []minidump.TrheadFrame {
+	var f []minidump.TrheadFrame
+	for _, function := range functions {
+		f = append(f, minidump.TrheadFrame{Function: function})
+	}
+	return f
+}
+
+func TestScriptRun(t *testing.T) {
+	script := loadTestScript(t)
+
+	hang := minidump.Report{Kind: format.KindHang, Signature: "hang | abort"}
+	hang.CrashingThread.Frames = frames("WaitForSingleObject")
+	hang.Threads = []minidump.ThreadInfo{{Frames: frames("abort", "main")}}
+
+	crash := minidump.Report{Signature: "abort"}
+	crash.CrashingThread.Frames = frames("abort", "main")
+	crash.Threads = []minidump.ThreadInfo{{Frames: frames("main")}}
+
+	tests := []struct {
+		name      string
+		report    minidump.Report
+		action    Action
+		signature string
+		tags      []string
+	}{
+		{"set signature", crash, Continue, "abort | assert", nil},
+		// frames of hangs are of the main thread as the signature
+		{"hang", hang, Continue, "abort | assert", []string{"hang_abort"}},
+		{"drop", minidump.Report{Signature: "main", Annotations: map[string]string{"drop": "yes"}}, Drop, "main", nil},
+		{"no frames", minidump.Report{Signature: "main"}, Continue, "main", nil},
+	}
+
+	for _, test := range tests {
+		report := test.report
+		result := script.Run(context.Background(), &report, &format.Info{}, nil)
+		if result.Err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, result.Err)
+			continue
+		}
+
+		if result.Action != test.action {
+			t.Errorf("%s: action %s, expected %s", test.name, result.Action, test.action)
+		}
+
+		if report.Signature != test.signature {
+			t.Errorf("%s: signature %q, expected %q", test.name, report.Signature, test.signature)
+		}
+
+		if !reflect.DeepEqual(report.Tags, test.tags) {
+			t.Errorf("%s: tags %v, expected %v", test.name, report.Tags, test.tags)
+		}
+	}
+}
+
+func TestScriptFrozenGlobals(t *testing.T) {
+	script := loadTestScript(t)
+
+	// a report can't change module values seen by the next reports
+	for i := 0; i < 2; i++ {
+		report := &minidump.Report{Signature: "main", Annotations: map[string]string{"remember": "yes"}}
+		result := script.Run(context.Background(), report, &format.Info{}, nil)
+
+		if result.Err == nil || !strings.Contains(result.Err.Error(), "frozen") {
+			t.Errorf("run %d: error %v, expected frozen list", i, result.Err)
+		}
+
+		if result.Action != Continue {
+			t.Errorf("run %d: action %s, expected continue", i, result.Action)
+		}
+	}
+}
+
+func TestLoadScript(t *testing.T) {
+	tests := []struct {
+		path  string
+		valid bool
+	}{
+		{filepath.Join("..", "etc", "rules.star"), true},
+		{filepath.Join("testdata", "missing.star"), false},
+	}
+
+	for _, test := range tests {
+		_, err := LoadScript(test.path)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: error %v, expected valid %t", test.path, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+)
+
+// Built-in detectors of personal data. IPv4 of ip matches four-part versions like 5.12.0.1
+// in logs and module paths too, so ip isn't in the defaults and ipv6 is used instead
+const (
+	ScrubEmail = "email"
+	ScrubIp    = "ip"
+	ScrubIpv6  = "ipv6"
+	ScrubToken = "token"
+	ScrubHome  = "home"
+)
+
+const ipv6Regexp = `\b(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}\b`
+
+var builtinScrubRules = []struct {
+	name    string
+	regexp  string
+	replace string
+}{
+	{ScrubEmail, `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`, "<email>"},
+	{ScrubIp, `\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`, "<ip>"},
+	{ScrubIp, ipv6Regexp, "<ip>"},
+	{ScrubIpv6, ipv6Regexp, "<ip>"},
+	{ScrubToken, `(?i)([?&](?:access_token|token|api_key|apikey|key|auth|secret|password|session|sid)=)[^&#\s]+`, "${1}<token>"},
+	{ScrubToken, `(?i)(authorization:\s*(?:bearer|basic|token)\s+)\S+`, "${1}<token>"},
+	{ScrubToken, `\beyJ[\w\-]+\.[\w\-]+\.[\w\-]+`, "<token>"},
+	{ScrubHome, `(?i)([a-z]:[\\/]+(?:users|documents and settings)[\\/]+)[^\\/"\r\n]+`, "${1}<user>"},
+	{ScrubHome, `(/(?:home|Users)/)[^/\s"]+`, "${1}<user>"},
+}
+
+type ScrubRule struct {
+	Name    string
+	Regexp  *regexp.Regexp
+	Replace string
+}
+
+// Built-in detectors of the scrub stage without builtin option
+var defaultScrubBuiltins = []string{ScrubEmail, ScrubIpv6, ScrubToken, ScrubHome}
+
+// Removes personal data from logs, paths and raw crashes.
+// Names of the rules which fired are saved in the report.
+// Nothing is scrubbed unless the stage is in a pipeline, usually the last one
+type Scrubber struct {
+	Rules []*ScrubRule
+}
+
+func init() {
+	RegisterV2("scrub", func(options json.RawMessage) (StageV2, error) {
+		opts := struct {
+			Builtin []string `json:"builtin"`
+			Rules   []struct {
+				Name    string `json:"name"`
+				Regexp  string `json:"regexp"`
+				Replace string `json:"replace"`
+			} `json:"rules"`
+		}{Builtin: defaultScrubBuiltins}
+
+		err := decodeOptions(options, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		rules, err := BuiltinScrubRules(opts.Builtin)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range opts.Rules {
+			rule, err := NewScrubRule(r.Name, r.Regexp, r.Replace)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+
+		return NewScrubber(rules), nil
+	})
+}
+
+func NewScrubRule(name, rx, replace string) (*ScrubRule, error) {
+	compiled, err := regexp.Compile(rx)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid scrub rule %s: %s", name, err.Error())
+	}
+
+	return &ScrubRule{
+		Name:    name,
+		Regexp:  compiled,
+		Replace: replace,
+	}, nil
+}
+
+// Rules of the built-in detectors: email, ip, ipv6, token, home
+func BuiltinScrubRules(names []string) ([]*ScrubRule, error) {
+	var rules []*ScrubRule
+	for _, name := range names {
+		found := false
+		for _, b := range builtinScrubRules {
+			if b.name != name {
+				continue
+			}
+
+			found = true
+			rule, err := NewScrubRule(b.name, b.regexp, b.replace)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+
+		if !found {
+			return nil, fmt.Errorf("Unknown built-in scrub rule %s", name)
+		}
+	}
+
+	return rules, nil
+}
+
+func NewScrubber(rules []*ScrubRule) *Scrubber {
+	return &Scrubber{
+		Rules: rules,
+	}
+}
+
+func (s *Scrubber) Run(ctx context.Context, report *minidump.Report, info *format.Info, raw *Raw) Result {
+	fired := s.Scrub(report)
+	if len(fired) == 0 {
+		return Result{Action: Continue}
+	}
+	return Result{Action: Continue, Note: "scrubbed " + strings.Join(fired, ", ")}
+}
+
+// Scrub the report and return names of the rules which fired
+func (s *Scrubber) Scrub(report *minidump.Report) []string {
+	if len(s.Rules) == 0 {
+		return nil
+	}
+
+	fired := map[string]bool{}
+	scrub := func(text string) string {
+		for _, rule := range s.Rules {
+			if rule.Regexp.MatchString(text) {
+				fired[rule.Name] = true
+				text = rule.Regexp.ReplaceAllString(text, rule.Replace)
+			}
+		}
+		return text
+	}
+
+	report.Log = scrub(report.Log)
+	report.RawCrash = scrub(report.RawCrash)
+	report.Source = scrub(report.Source)
+	report.Signature = scrub(report.Signature)
+	report.Url = scrub(report.Url)
+	report.ErrorMessage = scrub(report.ErrorMessage)
+
+	for k, v := range report.Annotations {
+		report.Annotations[k] = scrub(v)
+	}
+
+	for i := range report.Breadcrumbs {
+		b := &report.Breadcrumbs[i]
+		b.Message = scrub(b.Message)
+		for k, v := range b.Data {
+			b.Data[k] = scrub(v)
+		}
+	}
+
+	for i := range report.Modules {
+		m := &report.Modules[i]
+		m.File = scrub(m.File)
+		m.DebugFile = scrub(m.DebugFile)
+	}
+
+	scrubFrames(report.CrashingThread.Frames, scrub)
+	for _, t := range report.Threads {
+		scrubFrames(t.Frames, scrub)
+	}
+
+	var names []string
+	for name := range fired {
+		names = append(names, name)
+		report.AddScrubbed(name)
+	}
+	sort.Strings(names)
+	sort.Strings(report.Scrubbed)
+
+	return names
+}
+
+func scrubFrames(frames []minidump.TrheadFrame, scrub func(string) string) {
+	for i := range frames {
+		frames[i].File = scrub(frames[i].File)
+		frames[i].Module = scrub(frames[i].Module)
+	}
+}
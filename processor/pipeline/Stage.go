@@ -2,6 +2,7 @@
 package pipeline
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"yabs/common/format"
@@ -22,12 +23,58 @@ type SignatureAndSource struct {
 
 type MinidumpStackUnfolding struct {
 	Stage
+	// frames of these modules are used for the signature
+	Modules *regexp.Regexp
+}
+
+const defaultUnfoldingModules = "iq\\s*option"
+
+func init() {
+	Register("signature_and_source", func(options json.RawMessage) (Stage, error) {
+		return &SignatureAndSource{}, nil
+	})
+
+	Register("stack_unfolding", func(options json.RawMessage) (Stage, error) {
+		opts := struct {
+			Modules string `json:"modules"`
+		}{defaultUnfoldingModules}
+
+		err := decodeOptions(options, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		modules, err := regexp.Compile(opts.Modules)
+		if err != nil {
+			return nil, err
+		}
+
+		return &MinidumpStackUnfolding{Modules: modules}, nil
+	})
+}
+
+// Frames used for the signature. A hang has no crashing thread,
+// the main thread which is the first one in the dump is used instead
+func signatureFrames(report *minidump.Report) []minidump.TrheadFrame {
+	if report.Kind == format.KindHang && len(report.Threads) != 0 {
+		return report.Threads[0].Frames
+	}
+	return report.CrashingThread.Frames
+}
+
+// Signatures of reports which aren't crashes are prefixed by the kind, e.g. "hang | main"
+func kindSignature(report *minidump.Report, function string) string {
+	if len(report.Kind) == 0 || report.Kind == format.KindCrash {
+		return function
+	}
+	return report.Kind + " | " + function
 }
 
 func (m *SignatureAndSource) Process(report *minidump.Report, info *format.Info) bool {
-	if len(report.CrashingThread.Frames) > 0 {
-		frame := &report.CrashingThread.Frames[0]
-		signature := frame.Function
+	frames := signatureFrames(report)
+	if len(frames) > 0 {
+		frame := &frames[0]
+		signature := kindSignature(report, frame.Function)
 		source := fmt.Sprintf("%s:%d", frame.File,
 			frame.Line)
 		report.Signature = signature
@@ -38,17 +85,21 @@ func (m *SignatureAndSource) Process(report *minidump.Report, info *format.Info)
 }
 
 func (m *MinidumpStackUnfolding) Process(report *minidump.Report, info *format.Info) bool {
-	frames := report.CrashingThread.Frames
-	if len(report.CrashingThread.Frames) == 0 {
+	frames := signatureFrames(report)
+	if len(frames) == 0 {
 		// go to next stage
 		return false
 	}
 
-	iqoption := regexp.MustCompile("iq\\s*option")
+	modules := m.Modules
+	if modules == nil {
+		modules = regexp.MustCompile(defaultUnfoldingModules)
+	}
+
 	for _, frame := range frames {
 		module := strings.ToLower(frame.Module)
-		if iqoption.MatchString(module) {
-			report.Signature = frame.Function
+		if modules.MatchString(module) {
+			report.Signature = kindSignature(report, frame.Function)
 			report.Source = fmt.Sprintf("%s:%d", frame.File,
 				frame.Line)
 			return true
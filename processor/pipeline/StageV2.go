@@ -0,0 +1,69 @@
+package pipeline
+
+import (
+	"context"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+)
+
+// What the pipeline does after a stage
+type Action int
+
+const (
+	Continue Action = iota
+	Stop
+	Drop
+	// signature is final, the rest stages of the first version are skipped
+	Final
+)
+
+func (a Action) String() string {
+	switch a {
+	case Stop:
+		return "stop"
+	case Final:
+		return "final"
+	case Drop:
+		return "drop"
+	default:
+		return "continue"
+	}
+}
+
+// Raw inputs of the report, files are removed after the pipeline
+type Raw struct {
+	DumpPath    string
+	LogPath     string
+	Attachments map[string]string
+	// json output of stackwalker
+	Stackwalk []byte
+}
+
+// Result of a stage. Error is recorded in the notes and the pipeline continues
+type Result struct {
+	Action Action
+	Note   string
+	Err    error
+}
+
+// Pipeline stage which can see raw inputs, fail and explain what it did.
+// Run must return when ctx is done
+type StageV2 interface {
+	Run(ctx context.Context, report *minidump.Report, info *format.Info, raw *Raw) Result
+}
+
+type stageAdapter struct {
+	stage Stage
+}
+
+// Run the stage of the first version as StageV2
+func Adapt(stage Stage) StageV2 {
+	return &stageAdapter{stage}
+}
+
+func (a *stageAdapter) Run(ctx context.Context, report *minidump.Report, info *format.Info, raw *Raw) Result {
+	if a.stage.Process(report, info) {
+		return Result{Action: Final}
+	}
+	return Result{Action: Continue}
+}
@@ -0,0 +1,128 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+)
+
+// Tag of reports with third-party modules
+const ThirdPartyTag = "third_party"
+
+const (
+	defaultOwnModules = `(?i)iq\s*option`
+	// system directories of Windows, macOS and Linux
+	defaultOsModules = `(?i)^[a-z]:[\\/]+windows[\\/]|^/System/Library/|^/usr/lib/|^/lib(32|64)?/|^/usr/lib(32|64)?/`
+)
+
+// Finds modules which are neither ours nor of the OS, e.g. injected by antivirus or overlays.
+// If the crashing frame is inside one of them, the module is blamed in the signature
+type ThirdPartyModules struct {
+	own     *regexp.Regexp
+	os      *regexp.Regexp
+	vendors []vendorRule
+}
+
+type vendorRule struct {
+	module *regexp.Regexp
+	vendor string
+}
+
+func init() {
+	RegisterV2("third_party", func(options json.RawMessage) (StageV2, error) {
+		opts := struct {
+			Own     string `json:"own"`
+			Os      string `json:"os"`
+			// the first matched pattern names the vendor
+			Vendors []struct {
+				Pattern string `json:"pattern"`
+				Vendor  string `json:"vendor"`
+			} `json:"vendors"`
+		}{Own: defaultOwnModules, Os: defaultOsModules}
+
+		err := decodeOptions(options, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		own, err := regexp.Compile(opts.Own)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid own: %s", err.Error())
+		}
+
+		os, err := regexp.Compile(opts.Os)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid os: %s", err.Error())
+		}
+
+		stage := &ThirdPartyModules{own: own, os: os}
+		for _, v := range opts.Vendors {
+			rx, err := regexp.Compile(v.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid vendor pattern %s: %s", v.Pattern, err.Error())
+			}
+			stage.vendors = append(stage.vendors, vendorRule{rx, v.Vendor})
+		}
+
+		return stage, nil
+	})
+}
+
+func (t *ThirdPartyModules) Run(ctx context.Context, report *minidump.Report, info *format.Info, raw *Raw) Result {
+	thirdParty := map[string]bool{}
+	for _, m := range report.Modules {
+		if t.own.MatchString(m.File) || t.os.MatchString(m.File) {
+			continue
+		}
+
+		name := moduleName(m.File)
+		thirdParty[strings.ToLower(name)] = true
+		report.ThirdPartyModules = append(report.ThirdPartyModules, minidump.ThirdPartyModule{
+			Name:    name,
+			Path:    m.File,
+			Version: m.Version,
+			Vendor:  t.vendor(m.File),
+			DebugId: m.DebugId,
+		})
+	}
+
+	if len(report.ThirdPartyModules) == 0 {
+		return Result{Action: Continue}
+	}
+
+	report.AddTag(ThirdPartyTag)
+	note := fmt.Sprintf("%d third-party modules", len(report.ThirdPartyModules))
+
+	// OOM crashes stay in their own group
+	frames := signatureFrames(report)
+	if len(frames) != 0 && len(report.Oom) == 0 {
+		blamed := moduleName(frames[0].Module)
+		if thirdParty[strings.ToLower(blamed)] {
+			report.Signature = blamed + " | " + report.Signature
+			note += ", crashed in " + blamed
+		}
+	}
+
+	return Result{Action: Continue, Note: note}
+}
+
+func (t *ThirdPartyModules) vendor(file string) string {
+	for _, v := range t.vendors {
+		if v.module.MatchString(file) {
+			return v.vendor
+		}
+	}
+	return ""
+}
+
+// File name of the module without directory, paths may be of any OS
+func moduleName(file string) string {
+	if i := strings.LastIndexAny(file, `\/`); i >= 0 {
+		return file[i+1:]
+	}
+	return file
+}
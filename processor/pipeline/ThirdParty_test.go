@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"yabs/common/format"
+	"yabs/common/format/minidump"
+)
+
+func newThirdParty(t *testing.T, options string) StageV2 {
+	stage, err := NewStage("third_party", json.RawMessage(options))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stage
+}
+
+func TestThirdPartyVendorOrder(t *testing.T) {
+	// both patterns match aswhook.dll, the first one wins
+	options := `{"vendors": [
+		{"pattern": "(?i)aswhook", "vendor": "Avast"},
+		{"pattern": "(?i)hook", "vendor": "Hooks"},
+		{"pattern": "(?i)nvinject", "vendor": "NVIDIA"}
+	]}`
+
+	tests := []struct {
+		file   string
+		vendor string
+	}{
+		{`C:\Program Files\Avast\aswhook.dll`, "Avast"},
+		{`C:\Tools\rtsshooks.dll`, "Hooks"},
+		{`C:\Drivers\nvinject.dll`, "NVIDIA"},
+		{`C:\Tools\overlay.dll`, ""},
+	}
+
+	// the order doesn't depend on map iteration, so every run gives the same vendor
+	for run := 0; run < 20; run++ {
+		stage := newThirdParty(t, options)
+		for _, test := range tests {
+			report := &minidump.Report{}
+			report.Modules = []minidump.ModuleInfo{{File: test.file}}
+			stage.Run(context.Background(), report, &format.Info{}, nil)
+
+			if len(report.ThirdPartyModules) != 1 {
+				t.Fatalf("%s: third-party modules %v", test.file, report.ThirdPartyModules)
+			}
+
+			if vendor := report.ThirdPartyModules[0].Vendor; vendor != test.vendor {
+				t.Errorf("%s: vendor %q, expected %q", test.file, vendor, test.vendor)
+			}
+		}
+	}
+}
+
+func TestThirdPartySignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		module    string
+		oom       string
+		signature string
+		tagged    bool
+	}{
+		{"own module", `C:\Program Files\IQ Option\app.exe`, "", "main", false},
+		{"os module", `C:\Windows\System32\ntdll.dll`, "", "main", false},
+		{"third-party module", `C:\Program Files\Avast\aswhook.dll`, "", "aswhook.dll | main", true},
+		{"oom", `C:\Program Files\Avast\aswhook.dll`, OomSmall, "main", true},
+	}
+
+	stage := newThirdParty(t, ``)
+	for _, test := range tests {
+		report := &minidump.Report{Signature: "main", Oom: test.oom}
+		report.Modules = []minidump.ModuleInfo{{File: test.module}}
+		report.CrashingThread.Frames = []minidump.TrheadFrame{{Module: moduleName(test.module)}}
+
+		stage.Run(context.Background(), report, &format.Info{}, nil)
+
+		if report.Signature != test.signature {
+			t.Errorf("%s: signature %q, expected %q", test.name, report.Signature, test.signature)
+		}
+
+		tagged := len(report.Tags) == 1 && report.Tags[0] == ThirdPartyTag
+		if tagged != test.tagged {
+			t.Errorf("%s: tags %v", test.name, report.Tags)
+		}
+	}
+}
+
+func TestThirdPartyInvalid(t *testing.T) {
+	tests := []string{
+		`{"own": "("}`,
+		`{"vendors": [{"pattern": "(", "vendor": "Broken"}]}`,
+		`{"vendors": {"(?i)aswhook": "Avast"}}`,
+	}
+
+	for _, options := range tests {
+		_, err := NewStage("third_party", json.RawMessage(options))
+		if err == nil {
+			t.Errorf("%s: expected error", options)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"time"
 	"os"
 	"os/exec"
 	"encoding/json"
@@ -13,29 +15,31 @@ import (
 	"regexp"
 	"strings"
 	"io/ioutil"
+	"github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 )
 
 type MinidumpProcessor struct {
 	config     cfg.Config
 	repository *base.Repository
+	storage    base.Storage
+	throttler  *Throttler
 	linRx      *regexp.Regexp
 	winRx      *regexp.Regexp
 	macRx      *regexp.Regexp
-	pline      []pipeline.Stage
+	pipelines  *pipeline.Pipelines
 }
 
-func (s *MinidumpProcessor) initMinidumpProcessor(c cfg.Config, rep *base.Repository) {
+func (s *MinidumpProcessor) initMinidumpProcessor(c cfg.Config, rep *base.Repository, storage base.Storage, throttler *Throttler, pipelines *pipeline.Pipelines) {
 	s.config = c
 	s.repository = rep
+	s.storage = storage
+	s.throttler = throttler
+	s.pipelines = pipelines
 
 	s.linRx = regexp.MustCompile("linux")
 	s.winRx = regexp.MustCompile("windows")
 	s.macRx = regexp.MustCompile("mac")
-	s.pline = []pipeline.Stage{
-		&pipeline.SignatureAndSource{},
-		&pipeline.MinidumpStackUnfolding{},
-	}
 }
 
 func (s *MinidumpProcessor) handleMiniDump(t *task.Dump) *ReportWithId {
@@ -79,10 +83,17 @@ func (s *MinidumpProcessor) handleMiniDump(t *task.Dump) *ReportWithId {
 			}).Warning("Can't get version for debug id")
 			log.Warning(string(out))
 		} else {
+			raw := &pipeline.Raw{
+				DumpPath:    t.Path,
+				LogPath:     t.Log,
+				Attachments: t.Attachments,
+				Stackwalk:   out,
+			}
+
 			return s.processingReport(&dumpContext,
 				sym.Version,
 				info,
-				s.readLog(t), t)
+				s.readLog(t), t, raw)
 		}
 	} else {
 		log.WithField("context", dumpContext).
@@ -91,29 +102,45 @@ func (s *MinidumpProcessor) handleMiniDump(t *task.Dump) *ReportWithId {
 	return nil
 }
 
-func (s *MinidumpProcessor) processingReport(crash *minidump.Context, version string, info *format.Info, log string, t *task.Dump) *ReportWithId {
+func (s *MinidumpProcessor) processingReport(crash *minidump.Context, version string, info *format.Info, log string, t *task.Dump, raw *pipeline.Raw) *ReportWithId {
 
 	report := minidump.Report{
 		Context:      *crash,
 		UserId:       info.GetUserId(),
 		BuildVersion: version,
 		Platform:     s.getPlatform(crash.SystemInfo.OS),
+		Kind:         format.NormalizeKind(info.Kind),
 		CrashType:    crash.CrashInfo.Type,
 		Address:      crash.CrashInfo.Address,
 		DateAdded:    t.Time,
 		Gpu:          info.Gpu,
 		Ram:          info.Ram,
 		Log:          log,
+		InstallId:    info.InstallId,
+		Annotations:  info.Annotations,
+		Breadcrumbs:  info.Breadcrumbs,
 	}
 
-	for _, stage := range s.pline {
-		if stage.Process(&report, info) {
-			break
-		}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		time.Duration(s.config.PipelineTimeout())*time.Second)
+	defer cancel()
+
+	if !s.pipelines.For(report.Platform).Process(ctx, &report, info, raw) {
+		return nil
 	}
 
 	report.SystemInfo.CpuInfo = info.Cpu
-	id, _ := s.repository.AddReport(&report)
+	allowed, throttleKey := s.throttler.Allow(&report)
+	if !allowed {
+		return nil
+	}
+
+	id := uuid.NewV4().String()
+	report.Attachments = s.storeAttachments(id, t)
+	id, err := s.repository.AddReportWithId(id, &report)
+	if err == nil {
+		s.throttler.Stored(throttleKey, id)
+	}
 	return &ReportWithId{
 		Report: report,
 		Id:     id,
@@ -144,6 +171,46 @@ func (s *MinidumpProcessor) removeFiles(t *task.Dump) {
 			"error": err,
 		}).Warning("Can't remove file")
 	}
+
+	for _, path := range t.Attachments {
+		err = os.Remove(path)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"path":  path,
+				"error": err,
+			}).Warning("Can't remove file")
+		}
+	}
+}
+
+// Move attachments of the task into the blob storage
+func (s *MinidumpProcessor) storeAttachments(id string, t *task.Dump) []minidump.Attachment {
+	// without storage attachments are removed with the other files of the task
+	if s.storage == nil {
+		return nil
+	}
+
+	var attachments []minidump.Attachment
+	for name, path := range t.Attachments {
+		key := base.CrashKey(id, name)
+		size, err := s.storage.Put(key, path)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"name":  name,
+				"error": err,
+			}).Warning("Can't store attachment")
+			continue
+		}
+
+		delete(t.Attachments, name)
+		attachments = append(attachments, minidump.Attachment{
+			Name: name,
+			Key:  key,
+			Size: size,
+		})
+	}
+
+	return attachments
 }
 
 func (s *MinidumpProcessor) getPlatform(os string) string {
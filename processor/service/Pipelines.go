@@ -0,0 +1,35 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"yabs/processor/cfg"
+	"yabs/processor/pipeline"
+)
+
+// Create stages of all configured pipelines, fails if any stage can't be created
+func buildPipelines(c cfg.Config) (map[string]pipeline.Pipeline, error) {
+	if len(cfg.GlobalConfigPath) != 0 {
+		pipeline.ScriptDir = filepath.Dir(cfg.GlobalConfigPath)
+	}
+
+	byPlatform := map[string]pipeline.Pipeline{}
+	for platform, stages := range c.Pipelines() {
+		var pline pipeline.Pipeline
+		for _, s := range stages {
+			stage, err := pipeline.NewStage(s.Stage, s.Options)
+			if err != nil {
+				return nil, fmt.Errorf("Pipeline %s: %s", platform, err.Error())
+			}
+			pline = append(pline, pipeline.Step{Name: s.Stage, Stage: stage})
+		}
+		byPlatform[platform] = pline
+	}
+
+	// minidumps of unknown platforms would get no pipeline
+	if _, ok := byPlatform[pipeline.DefaultPipeline]; !ok {
+		return nil, fmt.Errorf("Pipeline %s is required", pipeline.DefaultPipeline)
+	}
+
+	return byPlatform, nil
+}
@@ -9,12 +9,10 @@ import (
 	"fmt"
 	"strings"
 	"yabs/common/data/base"
+	"yabs/common/utils"
 	"io/ioutil"
 	"encoding/json"
 	"time"
-	"crypto/md5"
-	"crypto/sha1"
-	"io"
 	"github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 )
@@ -27,8 +25,9 @@ type SymbolsProcessor struct {
 }
 
 type SymbolDescritpion struct {
-	Version  string `json:"version"`
-	Platform string `json:"platform"`
+	Version     string `json:"version"`
+	Platform    string `json:"platform"`
+	OnDuplicate string `json:"on_duplicate"`
 }
 
 const WebFileSymbol = "file.symbol"
@@ -133,7 +132,12 @@ func (s *SymbolsProcessor) handleWebSymbol(d *SymbolDescritpion, t *task.Symbol)
 		return
 	}
 
-	s.PutSymbolInStorage(dirPath, d.Version, id, "web")
+	s.PutSymbolInStorage(&base.Symbol{
+		DirPath:  dirPath,
+		Version:  d.Version,
+		DebugId:  id,
+		Platform: "web",
+	})
 }
 
 func (s *SymbolsProcessor) handleBreakpadSymbol(d *SymbolDescritpion, t *task.Symbol) {
@@ -146,30 +150,53 @@ func (s *SymbolsProcessor) handleBreakpadSymbol(d *SymbolDescritpion, t *task.Sy
 		return
 	}
 
-	exist, err := s.repository.IsExist(&base.Symbol{
-		DebugId: id,
-	})
-
+	md5, err := utils.FileMd5Hash(t.Path)
 	if err != nil {
-		panic(fmt.Sprintf("Can't check symbol id in repository %s", err.Error()))
 		s.removeFiles(t)
+		log.WithError(err).
+			Error("Can't calculate md5 for symbol file")
 		return
 	}
 
-	if exist {
-		log.WithField("debug id", id).
-			Warning("Symbol file with is exist")
+	sha1, err := utils.FileSha1Hash(t.Path)
+	if err != nil {
 		s.removeFiles(t)
+		log.WithError(err).
+			Error("Can't calculate sha1 for symbol file")
 		return
 	}
 
+	stored, err := s.repository.GetStoredSymbol(id)
 	if err != nil {
 		s.removeFiles(t)
-		log.WithField("task", t).
-			Warning("Can't extract version")
+		log.WithFields(log.Fields{
+			"debug id": id,
+			"error":    err,
+		}).Error("Can't check symbol id in repository")
 		return
 	}
 
+	if stored != nil {
+		if stored.Sha1 == sha1 {
+			log.WithField("debug id", id).
+				Info("Symbol file is already stored with the same content")
+			s.removeFiles(t)
+			return
+		}
+
+		if d.OnDuplicate != base.DuplicateReplace {
+			log.WithFields(log.Fields{
+				"debug id": id,
+				"policy":   d.OnDuplicate,
+			}).Warning("Symbol file with the same debug id and different content is exist")
+			s.removeFiles(t)
+			return
+		}
+
+		log.WithField("debug id", id).
+			Info("Replace symbol file")
+	}
+
 	dirPath := filepath.Join(s.symbols,
 		fullName,
 		id)
@@ -201,24 +228,35 @@ func (s *SymbolsProcessor) handleBreakpadSymbol(d *SymbolDescritpion, t *task.Sy
 		return
 	}
 
-	s.PutSymbolInStorage(dirPath, d.Version, id, platform)
+	if stored != nil && len(stored.DirPath) != 0 && stored.DirPath != dirPath {
+		err = os.RemoveAll(stored.DirPath)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"path":  stored.DirPath,
+				"error": err,
+			}).Warning("Can't remove replaced symbol dir")
+		}
+	}
+
+	s.PutSymbolInStorage(&base.Symbol{
+		DirPath:  dirPath,
+		Version:  d.Version,
+		DebugId:  id,
+		Platform: platform,
+		Md5:      md5,
+		Sha1:     sha1,
+	})
 }
 
-func (s *SymbolsProcessor) PutSymbolInStorage(dirPath, version, id, platform string) {
+func (s *SymbolsProcessor) PutSymbolInStorage(symbol *base.Symbol) {
 	log.WithFields(log.Fields{
-		"platform": platform,
-		"version":  version,
-		"id":       id,
+		"platform": symbol.Platform,
+		"version":  symbol.Version,
+		"id":       symbol.DebugId,
 	}).Debug("Put symbols in elastic")
 
-	now := time.Now()
-	err := s.repository.AddSymbol(&base.Symbol{
-		dirPath,
-		version,
-		id,
-		platform,
-		now.Format(time.RFC3339),
-	})
+	symbol.DataAdded = time.Now().Format(time.RFC3339)
+	err := s.repository.AddSymbol(symbol)
 
 	if err != nil {
 		panic(fmt.Sprintf("Can't append new symbol in database: %s",
@@ -287,42 +325,6 @@ func (s *SymbolsProcessor) readDescription(t *task.Symbol) (*SymbolDescritpion)
 	return &d
 }
 
-func (s *SymbolsProcessor) getFileMd5Hash(filepath string) string {
-	f, err := os.Open(filepath)
-	if err != nil {
-		log.WithError(err).
-			Error("Can't open file for calculate md5")
-		return ""
-	}
-	defer f.Close()
-	h := md5.New()
-	if _, err := io.Copy(h, f); err != nil {
-		log.WithField("path", filepath).
-			Error("Can't calculate md5 for file")
-		return ""
-	}
-
-	return strings.ToUpper(fmt.Sprintf("%x", h.Sum(nil)))
-}
-
-func (s *SymbolsProcessor) getFileSha1Hash(filepath string) string {
-	f, err := os.Open(filepath)
-	if err != nil {
-		log.WithError(err).
-			Error("Can't open file for calculate md5")
-		return ""
-	}
-	defer f.Close()
-	h := sha1.New()
-	if _, err := io.Copy(h, f); err != nil {
-		log.WithField("path", filepath).
-			Error("Can't calculate sha1 for file")
-		return ""
-	}
-
-	return strings.ToUpper(fmt.Sprintf("%x", h.Sum(nil)))
-}
-
 func (m *SymbolsProcessor) webPrefix() string {
 	dt := time.Now()
 	return fmt.Sprintf("%04d%02d%02d%02d%02d%02d", dt.Year(),
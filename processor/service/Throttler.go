@@ -0,0 +1,111 @@
+package service
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"time"
+	"yabs/common/data/base"
+	"yabs/common/format/minidump"
+	log "github.com/sirupsen/logrus"
+)
+
+// Throttler counts identical crashes of a user per hour. Only the first
+// max crashes are processed completely, the rest are only counted
+// in throttled_count of the last stored report
+type Throttler struct {
+	cache      base.Cashe
+	repository *base.Repository
+	max        int64
+	now        func() time.Time
+}
+
+// Returns false if the crash must not be stored and the key of identical crashes
+func (t *Throttler) Allow(report *minidump.Report) (bool, string) {
+	if t == nil || t.max <= 0 {
+		return true, ""
+	}
+
+	user := t.user(report)
+	if user == "" {
+		return true, ""
+	}
+
+	hour := t.now().UTC().Format("2006010215")
+	key := fmt.Sprintf("throttle:%x:%s:%s", sha1.Sum([]byte(report.Signature)), user, hour)
+
+	count, err := t.cache.Incr(key, time.Hour)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"key":   key,
+		}).Warning("Can't count crash for throttling")
+		return true, ""
+	}
+
+	if count > t.max {
+		log.WithFields(log.Fields{
+			"signature": report.Signature,
+			"user":      user,
+			"count":     count,
+		}).Debug("Throttled crash")
+		t.count(key)
+		return false, key
+	}
+
+	return true, key
+}
+
+// Remember the stored report of the key, throttled crashes are counted on it
+func (t *Throttler) Stored(key, id string) {
+	if t == nil || len(key) == 0 || len(id) == 0 {
+		return
+	}
+
+	err := t.cache.SetTtl(key+":report", id, time.Hour)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"key":   key,
+		}).Warning("Can't remember report for throttling")
+	}
+}
+
+func (t *Throttler) count(key string) {
+	id, err := t.cache.Get(key + ":report")
+	if err != nil || len(id) == 0 {
+		log.WithFields(log.Fields{
+			"error": err,
+			"key":   key,
+		}).Warning("Throttled crash has no stored report")
+		return
+	}
+
+	err = t.repository.IncrementThrottled(id)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"id":    id,
+		}).Warning("Can't count throttled crash")
+	}
+}
+
+func (t *Throttler) user(report *minidump.Report) string {
+	if report.UserId != 0 {
+		return fmt.Sprintf("u%d", report.UserId)
+	}
+
+	if len(report.InstallId) != 0 {
+		return "i" + report.InstallId
+	}
+
+	return ""
+}
+
+func newThrottler(cache base.Cashe, rep *base.Repository, max int) *Throttler {
+	return &Throttler{
+		cache:      cache,
+		repository: rep,
+		max:        int64(max),
+		now:        time.Now,
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"yabs/common/format/minidump"
+)
+
+// Counters of the cache without expiration, stored reports are never found
+type memoryCache struct {
+	counters map[string]int64
+}
+
+func (c *memoryCache) Get(key string) (string, error)                   { return "", errors.New("miss") }
+func (c *memoryCache) Set(key, value string) error                      { return nil }
+func (c *memoryCache) SetTtl(key, value string, ttl time.Duration) error { return nil }
+func (c *memoryCache) Delete(key string) error                          { return nil }
+
+func (c *memoryCache) Incr(key string, ttl time.Duration) (int64, error) {
+	c.counters[key]++
+	return c.counters[key], nil
+}
+
+func TestThrottler(t *testing.T) {
+	now := time.Date(2017, 6, 1, 10, 30, 0, 0, time.UTC)
+	throttler := newThrottler(&memoryCache{counters: map[string]int64{}}, nil, 2)
+	throttler.now = func() time.Time { return now }
+
+	crash := &minidump.Report{Signature: "abort"}
+	crash.UserId = 42
+
+	other := &minidump.Report{Signature: "main"}
+	other.UserId = 42
+
+	anonymous := &minidump.Report{Signature: "abort"}
+
+	tests := []struct {
+		name    string
+		report  *minidump.Report
+		after   time.Duration
+		allowed bool
+		keyed   bool
+	}{
+		{"first", crash, 0, true, true},
+		{"second", crash, 0, true, true},
+		{"above max", crash, 0, false, true},
+		{"other signature", other, 0, true, true},
+		{"without user", anonymous, 0, true, false},
+		{"same hour", crash, 20 * time.Minute, false, true},
+		// counters are per hour
+		{"next hour", crash, 10 * time.Minute, true, true},
+	}
+
+	for _, test := range tests {
+		now = now.Add(test.after)
+		allowed, key := throttler.Allow(test.report)
+
+		if allowed != test.allowed {
+			t.Errorf("%s: allowed %t, expected %t", test.name, allowed, test.allowed)
+		}
+
+		if (len(key) != 0) != test.keyed {
+			t.Errorf("%s: key %q", test.name, key)
+		}
+	}
+}
+
+func TestThrottlerOff(t *testing.T) {
+	report := &minidump.Report{Signature: "abort"}
+	report.UserId = 42
+
+	var throttler *Throttler
+	if allowed, _ := throttler.Allow(report); !allowed {
+		t.Error("nil throttler rejects crash")
+	}
+
+	throttler = newThrottler(&memoryCache{counters: map[string]int64{}}, nil, 0)
+	for i := 0; i < 3; i++ {
+		if allowed, _ := throttler.Allow(report); !allowed {
+			t.Errorf("crash %d: throttler without max rejects crash", i)
+		}
+	}
+}
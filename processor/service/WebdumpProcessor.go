@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"time"
 	"os"
 	"os/exec"
 	"regexp"
@@ -20,23 +22,22 @@ import (
 type WebdumpProcessor struct {
 	config        cfg.Config
 	repository    *base.Repository
+	throttler     *Throttler
 	ffAndChromeRx *regexp.Regexp
-	pline         []pipeline.Stage
+	pipelines     *pipeline.Pipelines
 }
 
-func (w *WebdumpProcessor) initWebdumpProcessor(c cfg.Config, rep *base.Repository) {
+func (w *WebdumpProcessor) initWebdumpProcessor(c cfg.Config, rep *base.Repository, throttler *Throttler, pipelines *pipeline.Pipelines) {
 	w.config = c
 	w.repository = rep
+	w.throttler = throttler
+	w.pipelines = pipelines
 
 	var err error = nil
 	w.ffAndChromeRx, err = regexp.Compile("^.* ((?:firefox|chrome)/[\\d\\.]+).*$")
 	if err != nil {
 		log.WithError(err).Panic("Can't compile firefox/chrome version regex")
 	}
-
-	w.pline = []pipeline.Stage{
-		pipeline.NewRx(w.config.WebBlackListSignaturs()),
-	}
 }
 
 func (w *WebdumpProcessor) handleWebDump(t *task.WebDump) *ReportWithId {
@@ -110,15 +111,21 @@ func (w *WebdumpProcessor) handleWebDump(t *task.WebDump) *ReportWithId {
 
 	rawDump :=  string(dump)
 
-	return w.processingReport(&dumpContext, info, rawDump, t)
+	raw := &pipeline.Raw{
+		DumpPath:  t.Path,
+		Stackwalk: out,
+	}
+
+	return w.processingReport(&dumpContext, info, rawDump, t, raw)
 }
 
-func (w *WebdumpProcessor) processingReport(crash *minidump.Context, info *format.Info, raw_crash string, t *task.WebDump) *ReportWithId {
+func (w *WebdumpProcessor) processingReport(crash *minidump.Context, info *format.Info, raw_crash string, t *task.WebDump, raw *pipeline.Raw) *ReportWithId {
 	var source string = ""
 
 	report := minidump.Report{
 		Context:      *crash,
 		Platform:     "web",
+		Kind:         format.NormalizeKind(info.Kind),
 		BuildVersion: info.Version,
 		Source:       source,
 		CrashType:    crash.CrashInfo.Type,
@@ -127,19 +134,39 @@ func (w *WebdumpProcessor) processingReport(crash *minidump.Context, info *forma
 		Gpu:          info.Gpu,
 		RawCrash:     raw_crash,
 		UserId: 	  info.GetUserId(),
+		InstallId:    info.InstallId,
+		Annotations:  info.Annotations,
+		Breadcrumbs:  info.Breadcrumbs,
+		Url:          info.Url,
+		ErrorMessage: info.ErrorMessage,
+		UserAgent:    info.Browser,
 	}
 
-	for _, stage := range w.pline {
-		if stage.Process(&report, info) {
-			break
-		}
+	if len(info.ErrorName) != 0 {
+		report.CrashType = info.ErrorName
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		time.Duration(w.config.PipelineTimeout())*time.Second)
+	defer cancel()
+
+	if !w.pipelines.For(report.Platform).Process(ctx, &report, info, raw) {
+		return nil
 	}
 
 	if report.Signature == "" && (strings.Count(raw_crash, "\n") <= 2) {
 		report.Signature = raw_crash
 	}
 
-	id, _ := w.repository.AddReport(&report)
+	allowed, throttleKey := w.throttler.Allow(&report)
+	if !allowed {
+		return nil
+	}
+
+	id, err := w.repository.AddReport(&report)
+	if err == nil {
+		w.throttler.Stored(throttleKey, id)
+	}
 	return &ReportWithId{
 		Report: report,
 		Id:     id,
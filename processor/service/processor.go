@@ -4,11 +4,15 @@ import (
 	"errors"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 	"github.com/streadway/amqp"
 	"yabs/common/task"
 	"yabs/common/data/base"
 	"yabs/processor/cfg"
+	"yabs/processor/pipeline"
+	"yabs/common/issues"
 	"fmt"
 	"yabs/common/format/minidump"
 	"encoding/json"
@@ -19,6 +23,8 @@ const (
 	SIGHUP            = syscall.SIGHUP
 	SIGTERM           = syscall.SIGTERM
 	DEVELOPER_VERSION = "999.999.999"
+	// Rules edited through the API are applied after this period
+	knownIssuesTtl    = time.Minute
 )
 
 type RabbitClient struct {
@@ -26,6 +32,7 @@ type RabbitClient struct {
 	taskChannel *amqp.Channel
 	taskQueue   amqp.Queue
 	messages    <-chan amqp.Delivery
+	fast        <-chan amqp.Delivery
 	postChannel *amqp.Channel
 }
 
@@ -33,10 +40,15 @@ type ProcessorService struct {
 	SymbolsProcessor
 	MinidumpProcessor
 	WebdumpProcessor
+	mutex      sync.Mutex
 	config     cfg.Config
 	rabbit     *RabbitClient
 	sig        <-chan os.Signal
 	repository *base.Repository
+	cache      base.Cashe
+	storage    base.Storage
+	throttler  *Throttler
+	pipelines  *pipeline.Pipelines
 }
 
 type ReportWithId struct {
@@ -79,11 +91,28 @@ func newRabbitClient(conf cfg.Config) *RabbitClient {
 	)
 	failOnError(err, "Failed to register a consumer")
 
-	return &RabbitClient{connection: conn,
+	client := &RabbitClient{connection: conn,
 		taskChannel:                 ch,
 		taskQueue:                   q,
 		messages:                    msgs,
 	}
+
+	if len(conf.RabbitFastQueue()) != 0 {
+		fq, err := ch.QueueDeclare(
+			conf.RabbitFastQueue(),
+			true,
+			false,
+			false,
+			false,
+			nil,
+		)
+		failOnError(err, "Failed to declare a fast queue")
+
+		client.fast, err = ch.Consume(fq.Name, "", false, false, false, false, nil)
+		failOnError(err, "Failed to register a fast queue consumer")
+	}
+
+	return client
 }
 
 func (p *ProcessorService) Init(config cfg.Config) error {
@@ -117,35 +146,81 @@ func (p *ProcessorService) Init(config cfg.Config) error {
 		return err
 	}
 	p.repository = rep
+	p.cache = cache
+
+	if len(p.config.StoragePath()) != 0 {
+		p.storage, err = base.NewFileStorage(p.config.StoragePath())
+		if err != nil {
+			log.WithError(err).Error("Can't create blob storage")
+			return err
+		}
+	} else {
+		log.Warning("Blob storage isn't configured, attachments of crashes are dropped")
+	}
+
+	if p.config.ThrottleEnable() {
+		p.throttler = newThrottler(cache, rep, p.config.ThrottleMaxPerHour())
+	}
+
+	pipeline.KnownIssues = issues.NewSet(rep.GetIssues, knownIssuesTtl)
+
+	pipelines, err := buildPipelines(p.config)
+	if err != nil {
+		log.WithError(err).Error("Can't create pipelines")
+		return err
+	}
+	p.pipelines = pipeline.NewPipelines(pipelines)
 
 	p.initSymbolProcessor(p.config.SymbolsPath(),
 		p.repository)
 	p.initMinidumpProcessor(p.config,
-		p.repository)
+		p.repository,
+		p.storage,
+		p.throttler,
+		p.pipelines)
 	p.initWebdumpProcessor(p.config,
-		p.repository)
+		p.repository,
+		p.throttler,
+		p.pipelines)
 
 	return nil
 }
 
 func (p *ProcessorService) Loop() {
 	for {
+		// clients of the fast queue are waiting, so it goes first
+		select {
+		case msg := <-p.rabbit.fast:
+			p.handleDelivery(msg)
+			continue
+		default:
+		}
+
 		select {
+		case msg := <-p.rabbit.fast:
+			p.handleDelivery(msg)
 		case msg := <-p.rabbit.messages:
-			if p.handleTask(msg.Body) == nil {
-				msg.Ack(false)
-			} else {
-				msg.Nack(false, true)
-			}
+			p.handleDelivery(msg)
 		case sig := <-p.sig:
 			p.handleSignal(sig)
 		}
 	}
 }
 
-func (p *ProcessorService) handleTask(message []byte) error {
+func (p *ProcessorService) handleDelivery(msg amqp.Delivery) {
+	if p.handleTask(msg) == nil {
+		msg.Ack(false)
+	} else {
+		msg.Nack(false, true)
+	}
+}
 
-	t := task.FromJson(message)
+func (p *ProcessorService) handleTask(msg amqp.Delivery) error {
+	// a task is processed with one configuration
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	t := task.FromJson(msg.Body)
 	if t == nil {
 		return errors.New("Invalid task")
 	}
@@ -154,6 +229,7 @@ func (p *ProcessorService) handleTask(message []byte) error {
 		d, _ := t.(*task.Dump)
 		r := p.handleMiniDump(d)
 		p.sendNext(r)
+		p.reply(msg, r)
 	case *task.Symbol:
 		s, _ := t.(*task.Symbol)
 		p.handleSymbol(s)
@@ -161,11 +237,46 @@ func (p *ProcessorService) handleTask(message []byte) error {
 		w, _ := t.(*task.WebDump)
 		r := p.handleWebDump(w)
 		p.sendNext(r)
+		p.reply(msg, r)
 	}
 
 	return nil
 }
 
+// Send the result to the collector if the client is waiting for it
+func (p *ProcessorService) reply(msg amqp.Delivery, report *ReportWithId) {
+	if len(msg.ReplyTo) == 0 {
+		return
+	}
+
+	result := task.Result{Dropped: report == nil}
+	if report != nil {
+		result.Id = report.Id
+		result.Signature = report.Signature
+		result.KnownIssue = report.KnownIssue
+	}
+
+	data, err := json.Marshal(&result)
+	if err != nil {
+		log.WithError(err).Error("Can't serialize task result")
+		return
+	}
+
+	err = p.rabbit.taskChannel.Publish(
+		"",
+		msg.ReplyTo,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType:   "text/json",
+			CorrelationId: msg.CorrelationId,
+			Body:          data,
+		})
+	if err != nil {
+		log.WithError(err).Warning("Can't reply to collector")
+	}
+}
+
 func (p *ProcessorService) handleSignal(sig os.Signal) {
 	log.WithField("signal", sig.String()).
 		Info("Catch")
@@ -197,7 +308,7 @@ func (p *ProcessorService) createPostProcessingExchange() {
 }
 
 func (p *ProcessorService) sendNext(report *ReportWithId) {
-	if p.rabbit.postChannel == nil {
+	if p.rabbit.postChannel == nil || report == nil || !p.postKind(report.Kind) {
 		return
 	}
 
@@ -213,6 +324,7 @@ func (p *ProcessorService) sendNext(report *ReportWithId) {
 		false,
 		false,
 		amqp.Publishing{
+			Headers:     amqp.Table{"kind": report.Kind},
 			ContentType: "text/json",
 			Body:        data,
 		})
@@ -222,52 +334,72 @@ func (p *ProcessorService) sendNext(report *ReportWithId) {
 	}
 }
 
+func (p *ProcessorService) postKind(kind string) bool {
+	for _, k := range p.config.RabbitPostKinds() {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Everything is built from the new config before anything is applied, so an invalid config keeps the old one
 func (p *ProcessorService) reloadConfiguration() {
 	log.Info("Try to reload configuration")
-	if len(cfg.GlobalConfigPath) != 0 {
-		conf, err := cfg.FromJson(cfg.GlobalConfigPath)
-		if err != nil {
-			log.WithError(err).
-				Error("Error reading configuration file")
-			return
-		}
-		//TODO need compare all configuration and apply only a changes
-		noErrors := true
-
-		if conf.LogLevel() != p.config.LogLevel() {
-			err := p.changeLevel(conf.LogLevel())
-			if err != nil {
-				noErrors = false
-			}
-		}
+	if len(cfg.GlobalConfigPath) == 0 {
+		return
+	}
 
-		if len(conf.WebBlackListSignaturs()) != len(p.config.WebBlackListSignaturs()) {
-			p.initWebdumpProcessor(conf, p.repository)
-		}
+	conf, err := cfg.FromJson(cfg.GlobalConfigPath)
+	if err != nil {
+		log.WithError(err).
+			Error("Error reading configuration file")
+		return
+	}
 
-		if noErrors {
-			cfg.GlobalConfig = conf
-			p.config = conf
-			log.Info("Reloaded configuration")
-		}
+	level, err := log.ParseLevel(conf.LogLevel())
+	if err != nil {
+		log.WithError(err).
+			Error("Can't parse level")
+		return
 	}
-}
 
-func (p *ProcessorService) changeLevel(l string) error {
-	level, err := log.ParseLevel(l)
+	pipelines, err := buildPipelines(conf)
 	if err != nil {
 		log.WithError(err).
-			Warn("Can't parse level")
-		return err
+			Error("Can't create pipelines")
+		return
 	}
 
-	log.WithFields(log.Fields{
-		"old level": p.config.LogLevel(),
-		"new level": l,
-	}).
-		Info("Change log level")
-	log.SetLevel(level)
-	return nil
+	var throttler *Throttler
+	if conf.ThrottleEnable() {
+		throttler = newThrottler(p.cache, p.repository, conf.ThrottleMaxPerHour())
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if conf.LogLevel() != p.config.LogLevel() {
+		log.WithFields(log.Fields{
+			"old level": p.config.LogLevel(),
+			"new level": conf.LogLevel(),
+		}).
+			Info("Change log level")
+		log.SetLevel(level)
+	}
+
+	p.pipelines.Set(pipelines)
+
+	p.throttler = throttler
+	p.MinidumpProcessor.throttler = throttler
+	p.WebdumpProcessor.throttler = throttler
+
+	cfg.GlobalConfig = conf
+	p.config = conf
+	// processors keep their own copy, e.g. for pipeline_timeout
+	p.MinidumpProcessor.config = conf
+	p.WebdumpProcessor.config = conf
+	log.Info("Reloaded configuration")
 }
 
 func failOnError(err error, msg string) {